@@ -0,0 +1,235 @@
+package astilog
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// Async overflow policies
+const (
+	AsyncOverflowPolicyBlock      = "block"
+	AsyncOverflowPolicyDropNewest = "drop_newest"
+	AsyncOverflowPolicyDropOldest = "drop_oldest"
+)
+
+const defaultAsyncQueueSize = 1024
+
+// asyncWriter writes to its underlying writer in a background goroutine using a
+// bounded queue
+type asyncWriter struct {
+	dropped        uint64     // First to be 64-bit aligned for atomic operations
+	c              *sync.Cond // Signals pending updates
+	ch             chan asyncItem
+	closed         bool
+	done           chan struct{}
+	errorHandler   ErrorHandler
+	m              *sync.RWMutex // Locks ch and closed
+	maxWriteLength int
+	pending        int // Number of entries either in the queue or being written
+	policy         string
+	w              io.WriteCloser
+}
+
+type asyncItem struct {
+	b []byte
+	e *entry
+}
+
+func newAsyncWriter(c Configuration, w io.WriteCloser) (aw *asyncWriter) {
+	// Get queue size
+	size := c.AsyncQueueSize
+	if size <= 0 {
+		size = defaultAsyncQueueSize
+	}
+
+	// Create
+	aw = &asyncWriter{
+		c:              sync.NewCond(&sync.Mutex{}),
+		ch:             make(chan asyncItem, size),
+		done:           make(chan struct{}),
+		errorHandler:   c.ErrorHandler,
+		m:              &sync.RWMutex{},
+		maxWriteLength: c.MaxWriteLength,
+		policy:         c.AsyncOverflowPolicy,
+		w:              w,
+	}
+
+	// Start
+	go aw.start()
+	return
+}
+
+func (w *asyncWriter) start() {
+	// Make sure to signal we're done
+	defer close(w.done)
+
+	// Loop through items
+	for i := range w.ch {
+		// Write
+		var err error
+		if i.e != nil {
+			if err = writeEntry(w.w, *i.e, i.b, w.maxWriteLength); err != nil {
+				i.e.cs.written(0, err)
+			}
+		} else {
+			_, err = w.w.Write(i.b)
+		}
+		if err != nil {
+			handleError(w.errorHandler, fmt.Errorf("astilog: writing failed: %w", err))
+		}
+
+		// Update pending
+		w.done1()
+	}
+}
+
+func (w *asyncWriter) add1() {
+	w.c.L.Lock()
+	w.pending++
+	w.c.L.Unlock()
+}
+
+func (w *asyncWriter) done1() {
+	w.c.L.Lock()
+	w.pending--
+	if w.pending == 0 {
+		w.c.Broadcast()
+	}
+	w.c.L.Unlock()
+}
+
+func (w *asyncWriter) drop() {
+	atomic.AddUint64(&w.dropped, 1)
+	w.done1()
+}
+
+// Write implements the io.Writer interface
+func (w *asyncWriter) Write(p []byte) (n int, err error) {
+	// Caller may reuse the slice
+	b := make([]byte, len(p))
+	copy(b, p)
+
+	// Add
+	if err = w.add(asyncItem{b: b}); err != nil {
+		return
+	}
+	n = len(p)
+	return
+}
+
+// buildsPayload implements the payloadBuilder interface
+func (w *asyncWriter) buildsPayload() bool {
+	return buildsPayload(w.w)
+}
+
+func (w *asyncWriter) writeEntry(e entry, b []byte) error {
+	return w.add(asyncItem{
+		b: b,
+		e: &e,
+	})
+}
+
+func (w *asyncWriter) add(i asyncItem) (err error) {
+	// Lock
+	w.m.RLock()
+	defer w.m.RUnlock()
+
+	// Writer is closed
+	if w.closed {
+		err = errors.New("async writer is closed")
+		return
+	}
+
+	// Update pending
+	w.add1()
+
+	// Add to queue
+	switch w.policy {
+	case AsyncOverflowPolicyDropNewest:
+		select {
+		case w.ch <- i:
+		default:
+			w.drop()
+		}
+	case AsyncOverflowPolicyDropOldest:
+		for {
+			select {
+			case w.ch <- i:
+				return
+			default:
+				select {
+				case <-w.ch:
+					w.drop()
+				default:
+				}
+			}
+		}
+	default:
+		w.ch <- i
+	}
+	return
+}
+
+// Flush blocks until all queued entries have been written
+func (w *asyncWriter) Flush() error {
+	w.c.L.Lock()
+	for w.pending > 0 {
+		w.c.Wait()
+	}
+	w.c.L.Unlock()
+	return nil
+}
+
+// Sync flushes the queue and syncs the underlying writer
+func (w *asyncWriter) Sync() error {
+	// Flush
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	// Sync
+	if s, ok := w.w.(syncer); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// Close drains the queue and closes the underlying writer
+func (w *asyncWriter) Close() error {
+	// Close queue
+	w.m.Lock()
+	if w.closed {
+		w.m.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.ch)
+	w.m.Unlock()
+
+	// Wait for the queue to be drained
+	<-w.done
+
+	// Close writer
+	return w.w.Close()
+}
+
+// Reopen implements the reopener interface
+func (w *asyncWriter) Reopen() error {
+	if r, ok := w.w.(reopener); ok {
+		return r.Reopen()
+	}
+	return nil
+}
+
+// Dropped returns the number of entries dropped because the queue was full, as well as
+// the ones dropped by the underlying writer
+func (w *asyncWriter) Dropped() (n uint64) {
+	n = atomic.LoadUint64(&w.dropped)
+	if d, ok := w.w.(dropper); ok {
+		n += d.Dropped()
+	}
+	return
+}
@@ -0,0 +1,207 @@
+package astilog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/asticode/go-astikit"
+)
+
+// blockingWriter blocks writes until it's unblocked
+type blockingWriter struct {
+	b       *bytes.Buffer
+	ch      chan struct{}
+	m       *sync.Mutex
+	started chan struct{}
+}
+
+func newBlockingWriter() *blockingWriter {
+	return &blockingWriter{
+		b:       &bytes.Buffer{},
+		ch:      make(chan struct{}),
+		m:       &sync.Mutex{},
+		started: make(chan struct{}, 100),
+	}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	w.started <- struct{}{}
+	<-w.ch
+	w.m.Lock()
+	defer w.m.Unlock()
+	return w.b.Write(p)
+}
+
+func (w *blockingWriter) Close() error { return nil }
+
+func (w *blockingWriter) unblock() { close(w.ch) }
+
+func (w *blockingWriter) String() string {
+	w.m.Lock()
+	defer w.m.Unlock()
+	return w.b.String()
+}
+
+func TestAsyncWriter(t *testing.T) {
+	for _, v := range []struct {
+		dropped uint64
+		e       string
+		policy  string
+	}{
+		{e: "1\n2\n3\n4\n", policy: AsyncOverflowPolicyBlock},
+		{dropped: 1, e: "1\n2\n3\n", policy: AsyncOverflowPolicyDropNewest},
+		{dropped: 1, e: "1\n3\n4\n", policy: AsyncOverflowPolicyDropOldest},
+	} {
+		t.Run(v.policy, func(t *testing.T) {
+			// Create writer
+			bw := newBlockingWriter()
+			w := newAsyncWriter(Configuration{
+				AsyncOverflowPolicy: v.policy,
+				AsyncQueueSize:      2,
+			}, bw)
+
+			// First entry is being written
+			w.Write([]byte("1\n")) //nolint: errcheck
+			<-bw.started
+
+			// Fill the queue
+			w.Write([]byte("2\n")) //nolint: errcheck
+			w.Write([]byte("3\n")) //nolint: errcheck
+
+			// Queue is full
+			if v.policy == AsyncOverflowPolicyBlock {
+				go func() {
+					// Wait for the write to be blocked
+					for {
+						w.c.L.Lock()
+						p := w.pending
+						w.c.L.Unlock()
+						if p == 4 {
+							break
+						}
+					}
+					bw.unblock()
+				}()
+				w.Write([]byte("4\n")) //nolint: errcheck
+			} else {
+				w.Write([]byte("4\n")) //nolint: errcheck
+				bw.unblock()
+			}
+
+			// Flush
+			w.Flush() //nolint: errcheck
+			if e, g := v.e, bw.String(); e != g {
+				t.Errorf("expected %s, got %s", e, g)
+			}
+			if e, g := v.dropped, w.Dropped(); e != g {
+				t.Errorf("expected %d, got %d", e, g)
+			}
+
+			// Close
+			if err := w.Close(); err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if _, err := w.Write([]byte("5\n")); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestAsyncLogger(t *testing.T) {
+	// Bypass exit
+	old := exit
+	exit = func() {}
+	defer func() { exit = old }()
+
+	// Create logger
+	l := New(Configuration{
+		Async:  true,
+		Format: FormatMinimalist,
+	})
+	defer l.Close()
+	l.ss[0].w.Close()
+	bw := newBlockingWriter()
+	l.ss[0].w = newAsyncWriter(Configuration{}, bw)
+
+	// Write
+	var es []string
+	for i := 0; i < 10; i++ {
+		l.Info(i)
+		es = append(es, fmt.Sprintf("%d", i))
+	}
+
+	// Fatal flushes
+	go bw.unblock()
+	l.Fatal("fatal")
+	es = append(es, "fatal")
+	if g := strings.Split(strings.TrimSuffix(bw.String(), "\n"), "\n"); !reflect.DeepEqual(es, g) {
+		t.Errorf("expected %+v, got %+v", es, g)
+	}
+	if e, g := uint64(0), l.Dropped(); e != g {
+		t.Errorf("expected %d, got %d", e, g)
+	}
+
+	// Sink writer is async
+	l = New(Configuration{Async: true})
+	defer l.Close()
+	switch tp := l.ss[0].w.(type) {
+	case *asyncWriter:
+	default:
+		t.Errorf("expected *asyncWriter, got %T", tp)
+	}
+	if !reflect.DeepEqual(l.ss[0].w.(*asyncWriter).w, astikit.NopCloser(os.Stdout)) {
+		t.Error("expected false, got true")
+	}
+}
+
+type mockedEntryWriter struct {
+	io.WriteCloser
+	es []entry
+}
+
+func (w *mockedEntryWriter) writeEntry(e entry, b []byte) error {
+	w.es = append(w.es, e)
+	return nil
+}
+
+func TestAsyncWriterEntries(t *testing.T) {
+	ew := &mockedEntryWriter{WriteCloser: astikit.NopCloser(ioutil.Discard)}
+	w := newAsyncWriter(Configuration{}, ew)
+	e := entry{
+		l:   astikit.LoggerLevelWarn,
+		msg: "msg",
+	}
+	w.writeEntry(e, []byte("msg\n")) //nolint: errcheck
+	w.Close()
+	if !reflect.DeepEqual([]entry{e}, ew.es) {
+		t.Errorf("expected %+v, got %+v", []entry{e}, ew.es)
+	}
+}
+
+type mockedDropper struct {
+	io.WriteCloser
+	dropped uint64
+}
+
+func (d mockedDropper) Dropped() uint64 { return d.dropped }
+
+func TestAsyncWriterDropped(t *testing.T) {
+	w := newAsyncWriter(Configuration{}, mockedDropper{
+		WriteCloser: astikit.NopCloser(ioutil.Discard),
+		dropped:     3,
+	})
+	defer w.Close()
+	atomic.AddUint64(&w.dropped, 2)
+	if e, g := uint64(5), w.Dropped(); e != g {
+		t.Errorf("expected %d, got %d", e, g)
+	}
+}
@@ -0,0 +1,382 @@
+package astilog
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	batchDefaultBufferSize = 8 << 20
+	batchDefaultInterval   = time.Second
+	batchDefaultMaxRetries = 5
+	batchDefaultSize       = 1 << 20
+	batchMaxBackoff        = 30 * time.Second
+	batchMinBackoff        = 500 * time.Millisecond
+)
+
+// batchItem represents an entry waiting to be sent. b is the entry as it will be sent
+// and is used to compute the batch size.
+type batchItem struct {
+	b []byte
+	e entry
+}
+
+// batchSendFunc sends a batch and returns the items that should be retried and the items
+// that failed but can't be retried. If it fails without returning any item, the whole
+// batch is considered dropped.
+type batchSendFunc func(is []batchItem) (retry, dropped []batchItem, err error)
+
+// batchSpiller stores items that couldn't be sent so that they're sent later on
+type batchSpiller interface {
+	// discard removes the first n items
+	discard(n int) error
+	// peek returns the first items without removing them
+	peek(maxSize int) ([]batchItem, error)
+	spill(is []batchItem) error
+}
+
+// batchWriter buffers entries and sends them in batches in a background goroutine.
+// Batches are sent when they reach the batch size, at each interval or when flushing.
+// Failed batches are retried with a jittered exponential backoff. Entries are dropped
+// when the buffer is full, or when they can't be sent and there's no spiller.
+type batchWriter struct {
+	dropped      uint64     // First to be 64-bit aligned for atomic operations
+	batchSize    int        // Max number of bytes in a batch
+	bufferSize   int        // Max number of buffered bytes
+	c            *sync.Cond // Locks closed, is, pending and size, and signals pending updates
+	closed       bool
+	closing      chan struct{}
+	done         chan struct{}
+	errorHandler ErrorHandler
+	interval     time.Duration
+	is           []batchItem
+	maxRetries   int
+	pending      int // Number of items either buffered or being sent
+	send         batchSendFunc
+	size         int // Number of buffered bytes
+	spiller      batchSpiller
+	trigger      chan struct{}
+}
+
+func newBatchWriter(c Configuration, send batchSendFunc, spiller batchSpiller) (w *batchWriter) {
+	// Create
+	w = &batchWriter{
+		batchSize:    c.BatchSize,
+		bufferSize:   c.BatchBufferSize,
+		c:            sync.NewCond(&sync.Mutex{}),
+		closing:      make(chan struct{}),
+		done:         make(chan struct{}),
+		errorHandler: c.ErrorHandler,
+		interval:     c.BatchInterval,
+		maxRetries:   c.BatchMaxRetries,
+		send:         send,
+		spiller:      spiller,
+		trigger:      make(chan struct{}, 1),
+	}
+
+	// Default values
+	if w.batchSize <= 0 {
+		w.batchSize = batchDefaultSize
+	}
+	if w.bufferSize <= 0 {
+		w.bufferSize = batchDefaultBufferSize
+	}
+	if w.interval <= 0 {
+		w.interval = batchDefaultInterval
+	}
+	if w.maxRetries == 0 {
+		w.maxRetries = batchDefaultMaxRetries
+	} else if w.maxRetries < 0 {
+		w.maxRetries = 0
+	}
+
+	// Start
+	go w.start()
+	return
+}
+
+func (w *batchWriter) start() {
+	// Make sure to signal we're done
+	defer close(w.done)
+
+	// Create ticker
+	t := time.NewTicker(w.interval)
+	defer t.Stop()
+
+	// Loop
+	for {
+		// Wait
+		var closing bool
+		select {
+		case <-w.closing:
+			closing = true
+		case <-t.C:
+		case <-w.trigger:
+		}
+
+		// Send spilled items first
+		if w.spiller != nil {
+			w.sendSpilled()
+		}
+
+		// Send buffered items
+		w.sendBuffered()
+
+		// Writer is closing
+		if closing {
+			return
+		}
+	}
+}
+
+func (w *batchWriter) sendBuffered() {
+	for {
+		// Get next batch
+		w.c.L.Lock()
+		var n, size int
+		for n < len(w.is) && (n == 0 || size+len(w.is[n].b) <= w.batchSize) {
+			size += len(w.is[n].b)
+			n++
+		}
+		is := w.is[:n:n]
+		w.is = w.is[n:]
+		w.size -= size
+		w.c.L.Unlock()
+
+		// Nothing to send
+		if len(is) == 0 {
+			return
+		}
+
+		// Send
+		w.sendWithRetries(is)
+
+		// Update pending
+		w.c.L.Lock()
+		w.pending -= len(is)
+		if w.pending == 0 {
+			w.c.Broadcast()
+		}
+		w.c.L.Unlock()
+	}
+}
+
+func (w *batchWriter) sendSpilled() {
+	for {
+		// Get spilled items
+		is, err := w.spiller.peek(w.batchSize)
+		if err != nil {
+			handleError(w.errorHandler, fmt.Errorf("astilog: reading spilled items failed: %w", err))
+			return
+		}
+
+		// Nothing to send
+		if len(is) == 0 {
+			return
+		}
+
+		// Send
+		retry, dropped, err := w.send(is)
+		if len(retry) > 0 {
+			return
+		} else if err != nil && len(dropped) == 0 {
+			dropped = is
+		}
+
+		// Count
+		w.count(is, nil, dropped)
+		if err != nil {
+			handleError(w.errorHandler, fmt.Errorf("astilog: sending spilled items failed: %w", err))
+		}
+
+		// Discard
+		if err = w.spiller.discard(len(is)); err != nil {
+			handleError(w.errorHandler, fmt.Errorf("astilog: discarding spilled items failed: %w", err))
+			return
+		}
+	}
+}
+
+func (w *batchWriter) sendWithRetries(is []batchItem) {
+	for attempt := 0; ; attempt++ {
+		// Send
+		retry, dropped, err := w.send(is)
+
+		// Sending failed without returning any item, the whole batch is dropped
+		if err != nil && len(retry) == 0 && len(dropped) == 0 {
+			dropped = is
+		}
+
+		// Count
+		w.count(is, retry, dropped)
+
+		// Nothing to retry
+		if len(retry) == 0 {
+			if err != nil {
+				handleError(w.errorHandler, fmt.Errorf("astilog: sending batch failed, %d items have been dropped: %w", len(dropped), err))
+			}
+			return
+		}
+
+		// No more attempts
+		if attempt >= w.maxRetries {
+			w.giveUp(retry, err)
+			return
+		}
+
+		// Wait
+		select {
+		case <-time.After(batchBackoff(attempt)):
+		case <-w.closing:
+			w.giveUp(retry, err)
+			return
+		}
+		is = retry
+	}
+}
+
+func (w *batchWriter) giveUp(is []batchItem, err error) {
+	// Spill
+	if w.spiller != nil && len(is) > 0 {
+		errSpill := w.spiller.spill(is)
+		if errSpill == nil {
+			handleError(w.errorHandler, fmt.Errorf("astilog: sending batch failed, %d items have been spilled: %w", len(is), err))
+			return
+		}
+		handleError(w.errorHandler, fmt.Errorf("astilog: spilling failed: %w", errSpill))
+	}
+
+	// Drop
+	w.count(is, nil, is)
+	handleError(w.errorHandler, fmt.Errorf("astilog: sending batch failed, %d items have been dropped: %w", len(is), err))
+}
+
+// count counts items that have been dropped as well as items that have been sent, i.e.
+// items that have been neither retried nor dropped. Items are counted with the counters
+// of their entry.
+func (w *batchWriter) count(is, retry, dropped []batchItem) {
+	// Count dropped items
+	atomic.AddUint64(&w.dropped, uint64(len(dropped)))
+	for _, i := range dropped {
+		i.e.cs.failed(1)
+	}
+
+	// Count sent items
+	type count struct{ bytes, writes int }
+	cs := make(map[*sinkCounters]*count)
+	add := func(is []batchItem, sign int) {
+		for _, i := range is {
+			if i.e.cs == nil {
+				continue
+			}
+			c, ok := cs[i.e.cs]
+			if !ok {
+				c = &count{}
+				cs[i.e.cs] = c
+			}
+			c.bytes += sign * len(i.b)
+			c.writes += sign
+		}
+	}
+	add(is, 1)
+	add(retry, -1)
+	add(dropped, -1)
+	for k, c := range cs {
+		if c.writes > 0 {
+			k.add(c.writes, c.bytes)
+		}
+	}
+}
+
+// batchBackoff returns a jittered exponential backoff
+func batchBackoff(attempt int) time.Duration {
+	d := batchMinBackoff
+	for i := 0; i < attempt && d < batchMaxBackoff; i++ {
+		d *= 2
+	}
+	if d > batchMaxBackoff {
+		d = batchMaxBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func (w *batchWriter) add(i batchItem) (err error) {
+	// Lock
+	w.c.L.Lock()
+	defer w.c.L.Unlock()
+
+	// Writer is closed
+	if w.closed {
+		err = errors.New("batch writer is closed")
+		return
+	}
+
+	// Buffer is full
+	if w.size+len(i.b) > w.bufferSize {
+		atomic.AddUint64(&w.dropped, 1)
+		return
+	}
+
+	// Add
+	w.is = append(w.is, i)
+	w.pending++
+	w.size += len(i.b)
+
+	// Batch is full
+	if w.size >= w.batchSize {
+		w.wake()
+	}
+	return
+}
+
+func (w *batchWriter) wake() {
+	select {
+	case w.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Flush blocks until all buffered entries have been sent
+func (w *batchWriter) Flush() error {
+	w.c.L.Lock()
+	defer w.c.L.Unlock()
+	if w.pending > 0 {
+		w.wake()
+	}
+	for w.pending > 0 {
+		w.c.Wait()
+	}
+	return nil
+}
+
+// Sync implements the syncer interface
+func (w *batchWriter) Sync() error {
+	return w.Flush()
+}
+
+// Close sends buffered entries and stops the background goroutine
+func (w *batchWriter) Close() error {
+	// Mark as closed
+	w.c.L.Lock()
+	if w.closed {
+		w.c.L.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.closing)
+	w.c.L.Unlock()
+
+	// Wait for buffered items to be sent
+	<-w.done
+	return nil
+}
+
+// Dropped returns the number of entries dropped because the buffer was full or because
+// they couldn't be sent
+func (w *batchWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
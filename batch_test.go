@@ -0,0 +1,151 @@
+package astilog
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBatchWriter(t *testing.T) {
+	// Create writer
+	var bs [][]string
+	var attempts int
+	m := &sync.Mutex{}
+	w := newBatchWriter(Configuration{
+		BatchBufferSize: 8,
+		BatchInterval:   time.Hour,
+		BatchSize:       4,
+	}, func(is []batchItem) (retry, dropped []batchItem, err error) {
+		m.Lock()
+		defer m.Unlock()
+		attempts++
+		if attempts == 1 {
+			return is, nil, errors.New("retry")
+		}
+		var b []string
+		for _, i := range is {
+			b = append(b, string(i.b))
+		}
+		bs = append(bs, b)
+		return
+	}, nil)
+	defer w.Close()
+
+	// Add
+	for _, v := range []string{"1", "22", "333", "4444", "55555"} {
+		if err := w.add(batchItem{b: []byte(v)}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	// Flush
+	if err := w.Flush(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Assert
+	m.Lock()
+	defer m.Unlock()
+	if e, g := 1, len(bs); e > g {
+		t.Fatalf("expected at least %d batches, got %d", e, g)
+	}
+	var n int
+	for _, b := range bs {
+		var size int
+		for _, v := range b {
+			size += len(v)
+		}
+		if len(b) > 1 && size > 4 {
+			t.Errorf("batch %v is bigger than 4 bytes", b)
+		}
+		n += len(b)
+	}
+	if e, g := uint64(5-n), w.Dropped(); e != g {
+		t.Errorf("expected %d, got %d", e, g)
+	}
+	if e, g := 2, attempts; e > g {
+		t.Errorf("expected at least %d attempts, got %d", e, g)
+	}
+}
+
+func TestBatchWriterClose(t *testing.T) {
+	// Create writer
+	var count int
+	w := newBatchWriter(Configuration{
+		BatchInterval:   time.Hour,
+		BatchMaxRetries: -1,
+	}, func(is []batchItem) (retry, dropped []batchItem, err error) {
+		count += len(is)
+		return is, nil, errors.New("failed")
+	}, nil)
+
+	// Add
+	for i := 0; i < 3; i++ {
+		if err := w.add(batchItem{b: []byte("msg")}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	// Close
+	if err := w.Close(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if e, g := 3, count; e != g {
+		t.Errorf("expected %d, got %d", e, g)
+	}
+	if e, g := uint64(3), w.Dropped(); e != g {
+		t.Errorf("expected %d, got %d", e, g)
+	}
+	if err := w.add(batchItem{b: []byte("msg")}); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestBatchWriterNotRetryable(t *testing.T) {
+	// Create writer
+	var count int
+	var errs []error
+	w := newBatchWriter(Configuration{
+		BatchInterval: time.Hour,
+		ErrorHandler:  func(err error) { errs = append(errs, err) },
+	}, func(is []batchItem) (retry, dropped []batchItem, err error) {
+		count++
+		return nil, nil, errors.New("failed")
+	}, nil)
+
+	// Add
+	for i := 0; i < 3; i++ {
+		if err := w.add(batchItem{b: []byte("msg")}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	// Close
+	if err := w.Close(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if e, g := 1, count; e != g {
+		t.Errorf("expected %d, got %d", e, g)
+	}
+	if e, g := uint64(3), w.Dropped(); e != g {
+		t.Errorf("expected %d, got %d", e, g)
+	}
+	if e, g := 1, len(errs); e != g {
+		t.Fatalf("expected %d, got %d", e, g)
+	}
+	if e, g := "astilog: sending batch failed, 3 items have been dropped: failed", errs[0].Error(); e != g {
+		t.Errorf("expected %s, got %s", e, g)
+	}
+}
+
+func TestBatchBackoff(t *testing.T) {
+	for attempt, max := range []time.Duration{batchMinBackoff, 2 * batchMinBackoff, 4 * batchMinBackoff} {
+		if d := batchBackoff(attempt); d < max/2 || d > max {
+			t.Errorf("expected backoff between %s and %s, got %s", max/2, max, d)
+		}
+	}
+	if d := batchBackoff(100); d > batchMaxBackoff {
+		t.Errorf("expected backoff lower than %s, got %s", batchMaxBackoff, d)
+	}
+}
@@ -0,0 +1,87 @@
+package astilog
+
+import (
+	"os"
+	"strings"
+
+	"github.com/asticode/go-astikit"
+)
+
+// Colors
+const (
+	ColorAlways = "always"
+	ColorAuto   = "auto"
+	ColorNever  = "never"
+)
+
+// ANSI escape codes
+const (
+	colorDim     = "\x1b[2m"
+	colorGrey    = "\x1b[90m"
+	colorKey     = "\x1b[36m"
+	colorMagenta = "\x1b[35m"
+	colorRed     = "\x1b[31m"
+	colorReset   = "\x1b[0m"
+	colorYellow  = "\x1b[33m"
+)
+
+// isTerminal returns whether the file is a terminal
+var isTerminal = func(f *os.File) bool { return isTerminalFd(f.Fd()) }
+
+// colorEnabled returns whether the text format should be colorized. When the Color option
+// is "auto" or empty, NO_COLOR disables colors, FORCE_COLOR enables them and otherwise
+// they're only enabled when writing to stdout or stderr and it's a terminal.
+func colorEnabled(c Configuration) bool {
+	// Switch on option
+	switch c.Color {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+
+	// Check env
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if v := os.Getenv("FORCE_COLOR"); v != "" {
+		return v != "0" && !strings.EqualFold(v, "false")
+	}
+
+	// Check output
+	if c.Filename != "" || c.RingBuffer != nil {
+		return false
+	}
+	switch c.Out {
+	case "", OutStdout:
+		return isTerminal(os.Stdout)
+	case OutStderr:
+		return isTerminal(os.Stderr)
+	}
+	return false
+}
+
+// colorLevel returns the color of the level tag
+func colorLevel(l astikit.LoggerLevel) string {
+	switch l {
+	case astikit.LoggerLevelDebug:
+		return colorGrey
+	case astikit.LoggerLevelWarn:
+		return colorYellow
+	case astikit.LoggerLevelError:
+		return colorRed
+	case astikit.LoggerLevelFatal:
+		return colorMagenta
+	}
+	return ""
+}
+
+// colorAppend appends s to b surrounded by the color if colors are enabled
+func colorAppend(b []byte, s, color string, enabled bool) []byte {
+	if !enabled || color == "" {
+		return append(b, s...)
+	}
+	b = append(b, color...)
+	b = append(b, s...)
+	return append(b, colorReset...)
+}
@@ -0,0 +1,90 @@
+package astilog
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astikit"
+)
+
+func TestMain(m *testing.M) {
+	// Make sure text outputs don't depend on where tests are run
+	isTerminal = func(*os.File) bool { return false }
+	os.Unsetenv("FORCE_COLOR")
+	os.Unsetenv("NO_COLOR")
+	os.Exit(m.Run())
+}
+
+func TestColorEnabled(t *testing.T) {
+	// Mock terminal
+	oldIsTerminal := isTerminal
+	defer func() { isTerminal = oldIsTerminal }()
+	isTerminal = func(f *os.File) bool { return f == os.Stderr }
+
+	// Loop through cases
+	for _, v := range []struct {
+		c   Configuration
+		e   bool
+		env map[string]string
+	}{
+		{c: Configuration{}},
+		{c: Configuration{Out: OutStderr}, e: true},
+		{c: Configuration{Filename: "test.log", Out: OutStderr}},
+		{c: Configuration{Color: ColorAlways}, e: true},
+		{c: Configuration{Color: ColorNever, Out: OutStderr}},
+		{c: Configuration{Out: OutStderr}, env: map[string]string{"NO_COLOR": "1"}},
+		{c: Configuration{Color: ColorAlways}, e: true, env: map[string]string{"NO_COLOR": "1"}},
+		{c: Configuration{}, e: true, env: map[string]string{"FORCE_COLOR": "1"}},
+		{c: Configuration{Out: OutStderr}, env: map[string]string{"FORCE_COLOR": "0"}},
+		{c: Configuration{Color: ColorNever}, env: map[string]string{"FORCE_COLOR": "1"}},
+		{c: Configuration{Color: ColorAuto}, env: map[string]string{"FORCE_COLOR": "1", "NO_COLOR": "1"}},
+	} {
+		for k, ev := range v.env {
+			os.Setenv(k, ev)
+		}
+		if g := colorEnabled(v.c); g != v.e {
+			t.Errorf("expected %v, got %v for %+v and %+v", v.e, g, v.c, v.env)
+		}
+		for k := range v.env {
+			os.Unsetenv(k)
+		}
+	}
+}
+
+func TestColoredTextFormatter(t *testing.T) {
+	oldNow := now
+	defer func() { now = oldNow }()
+	now = func() time.Time { return time.Unix(5, 0).UTC() }
+
+	f := newTextFormatter(Configuration{Color: ColorAlways}, time.Unix(0, 0).UTC())
+	for _, v := range []struct {
+		e string
+		l astikit.LoggerLevel
+	}{
+		{e: "\x1b[90mDEBUG\x1b[0m[\x1b[2m0005\x1b[0m]msg  \x1b[36mk1\x1b[0m=v1 \x1b[36mk2\x1b[0m=v2\n", l: astikit.LoggerLevelDebug},
+		{e: " INFO[\x1b[2m0005\x1b[0m]msg  \x1b[36mk1\x1b[0m=v1 \x1b[36mk2\x1b[0m=v2\n", l: astikit.LoggerLevelInfo},
+		{e: "\x1b[33m WARN\x1b[0m[\x1b[2m0005\x1b[0m]msg  \x1b[36mk1\x1b[0m=v1 \x1b[36mk2\x1b[0m=v2\n", l: astikit.LoggerLevelWarn},
+		{e: "\x1b[31mERROR\x1b[0m[\x1b[2m0005\x1b[0m]msg  \x1b[36mk1\x1b[0m=v1 \x1b[36mk2\x1b[0m=v2\n", l: astikit.LoggerLevelError},
+		{e: "\x1b[35mFATAL\x1b[0m[\x1b[2m0005\x1b[0m]msg  \x1b[36mk1\x1b[0m=v1 \x1b[36mk2\x1b[0m=v2\n", l: astikit.LoggerLevelFatal},
+	} {
+		if g := string(testFormat(t, f, "msg", v.l, map[string]interface{}{
+			"k1": "v1",
+			"k2": "v2",
+		})); v.e != g {
+			t.Errorf("expected %q, got %q", v.e, g)
+		}
+	}
+}
+
+func TestIsTerminalFd(t *testing.T) {
+	f, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(fmt.Errorf("opening %s failed: %w", os.DevNull, err))
+	}
+	defer f.Close()
+	if isTerminalFd(f.Fd()) {
+		t.Errorf("expected %s not to be a terminal", os.DevNull)
+	}
+}
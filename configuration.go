@@ -1,64 +1,198 @@
 package astilog
 
 import (
+	"crypto/tls"
 	"flag"
+	"time"
 
 	"github.com/asticode/go-astikit"
 )
 
 // Flags
 var (
-	AppName         = flag.String("logger-app-name", "", "the logger app name")
-	Filename        = flag.String("logger-filename", "", "the logger filename")
-	Format          = flag.String("logger-format", "", "the logger format")
-	Level           = flag.String("logger-level", "", "the logger level")
-	MaxWriteLength  = flag.Int("logger-max-write-length", 0, "the logger max write length")
-	MessageKey      = flag.String("logger-message-key", "", "the logger message key")
-	Out             = flag.String("logger-out", "", "the logger out")
-	Source          = flag.Bool("logger-source", false, "if true, then source is added to fields")
-	TimestampFormat = flag.String("logger-timestamp-format", "", "the logger timestamp format")
-	Verbose         = flag.Bool("v", false, "if true, then log level is debug")
+	AppName             = flag.String("logger-app-name", "", "the logger app name")
+	Async               = flag.Bool("logger-async", false, "if true, then entries are written in a background goroutine")
+	AsyncOverflowPolicy = flag.String("logger-async-overflow-policy", "", "the logger async overflow policy")
+	AsyncQueueSize      = flag.Int("logger-async-queue-size", 0, "the logger async queue size")
+	BatchBufferSize     = flag.Int("logger-batch-buffer-size", 0, "the logger max number of bytes buffered by batching outputs")
+	BatchInterval       = flag.Duration("logger-batch-interval", 0, "the logger interval at which batches are sent")
+	BatchMaxRetries     = flag.Int("logger-batch-max-retries", 0, "the logger max number of retries when sending a batch failed")
+	BatchSize           = flag.Int("logger-batch-size", 0, "the logger max batch size in bytes")
+	Color               = flag.String("logger-color", "", "the logger color mode of the text format: auto, always or never")
+	ElasticsearchIndex  = flag.String("logger-elasticsearch-index", "", "the logger elasticsearch index")
+	ElasticsearchURL    = flag.String("logger-elasticsearch-url", "", "the logger elasticsearch url")
+	FallbackInterval    = flag.Duration("logger-fallback-interval", 0, "the logger interval at which the primary output is retried")
+	FileCompress        = flag.Bool("logger-file-compress", false, "if true, then old log files are compressed")
+	FileMaxAge          = flag.Duration("logger-file-max-age", 0, "the logger max age of old files")
+	FileMaxBackups      = flag.Int("logger-file-max-backups", 0, "the logger max number of file backups")
+	FileMaxSize         = flag.Int64("logger-file-max-size", 0, "the logger max file size in bytes")
+	FileSymlink         = flag.String("logger-file-symlink", "", "the logger symlink pointing at the current file")
+	Filename            = flag.String("logger-filename", "", "the logger filename")
+	FluentdAddress      = flag.String("logger-fluentd-address", "", "the logger fluentd address")
+	FluentdRequireAck   = flag.Bool("logger-fluentd-require-ack", false, "if true, then fluentd acks are required")
+	Format              = flag.String("logger-format", "", "the logger format")
+	GELFAddress         = flag.String("logger-gelf-address", "", "the logger gelf address")
+	GELFChunkSize       = flag.Int("logger-gelf-chunk-size", 0, "the logger gelf chunk size")
+	GELFCompression     = flag.String("logger-gelf-compression", "", "the logger gelf compression")
+	GELFNetwork         = flag.String("logger-gelf-network", "", "the logger gelf network")
+	Level               = flag.String("logger-level", "", "the logger level")
+	LokiURL             = flag.String("logger-loki-url", "", "the logger loki push url")
+	MaxWriteLength      = flag.Int("logger-max-write-length", 0, "the logger max write length")
+	MessageKey          = flag.String("logger-message-key", "", "the logger message key")
+	NetBufferSize       = flag.Int("logger-net-buffer-size", 0, "the logger max number of bytes buffered while a network output is disconnected")
+	OTLPURL             = flag.String("logger-otlp-url", "", "the logger otlp logs url")
+	Out                 = flag.String("logger-out", "", "the logger out")
+	ReopenOnSIGHUP      = flag.Bool("logger-reopen-on-sighup", false, "if true, then the log file is reopened on SIGHUP")
+	Source              = flag.Bool("logger-source", false, "if true, then source is added to fields")
+	SyncTimeout         = flag.Duration("logger-sync-timeout", 0, "the logger sync timeout")
+	SyslogAddress       = flag.String("logger-syslog-address", "", "the logger remote syslog address")
+	SyslogFacility      = flag.String("logger-syslog-facility", "", "the logger syslog facility")
+	SyslogNetwork       = flag.String("logger-syslog-network", "", "the logger remote syslog network")
+	SyslogTag           = flag.String("logger-syslog-tag", "", "the logger syslog tag")
+	Template            = flag.String("logger-template", "", "the logger layout of the template format")
+	TimestampFormat     = flag.String("logger-timestamp-format", "", "the logger timestamp format")
+	WebhookEncoding     = flag.String("logger-webhook-encoding", "", "the logger webhook encoding")
+	WebhookGzip         = flag.Bool("logger-webhook-gzip", false, "if true, then webhook requests are compressed with gzip")
+	WebhookSpillDir     = flag.String("logger-webhook-spill-dir", "", "the logger directory where webhook batches are spilled")
+	WebhookSpillMaxSize = flag.Int("logger-webhook-spill-max-size", 0, "the logger max size of webhook spilled batches in bytes")
+	WebhookURL          = flag.String("logger-webhook-url", "", "the logger webhook url")
+	Verbose             = flag.Bool("v", false, "if true, then log level is debug")
 )
 
 // Formats
 const (
+	FormatECS        = "ecs"
+	FormatGELF       = "gelf"
 	FormatJSON       = "json"
+	FormatLogfmt     = "logfmt"
 	FormatMinimalist = "minimalist"
+	FormatTemplate   = "template"
 	FormatText       = "text"
 )
 
 // Outs
 const (
-	OutStderr = "stderr"
-	OutStdout = "stdout"
-	OutSyslog = "syslog"
+	OutElasticsearch = "elasticsearch"
+	OutFluentd       = "fluentd"
+	OutGELF          = "gelf"
+	OutJournald      = "journald"
+	OutLoki          = "loki"
+	OutOTLP          = "otlp"
+	OutStderr        = "stderr"
+	OutStdout        = "stdout"
+	OutSyslog        = "syslog"
+	OutWebhook       = "webhook"
 )
 
 // Configuration represents the configuration of the logger
 type Configuration struct {
-	AppName         string              `toml:"app_name"`
-	Filename        string              `toml:"filename"`
-	Format          string              `toml:"format"`
-	Level           astikit.LoggerLevel `toml:"level"`
-	MaxWriteLength  int                 `toml:"max_write_length"`
-	MessageKey      string              `toml:"message_key"`
-	Out             string              `toml:"out"`
-	Source          bool                `toml:"source"`
-	TimestampFormat string              `toml:"timestamp_format"`
+	AppName             string              `toml:"app_name"`
+	Async               bool                `toml:"async"`
+	AsyncOverflowPolicy string              `toml:"async_overflow_policy"`
+	AsyncQueueSize      int                 `toml:"async_queue_size"`
+	BatchBufferSize     int                 `toml:"batch_buffer_size"`
+	BatchInterval       time.Duration       `toml:"batch_interval"`
+	BatchMaxRetries     int                 `toml:"batch_max_retries"`
+	BatchSize           int                 `toml:"batch_size"`
+	Color               string              `toml:"color"`
+	ElasticsearchIndex  string              `toml:"elasticsearch_index"`
+	ElasticsearchURL    string              `toml:"elasticsearch_url"`
+	ErrorHandler        ErrorHandler        `toml:"-"`
+	FallbackHandler     FallbackHandler     `toml:"-"`
+	FallbackInterval    time.Duration       `toml:"fallback_interval"`
+	Fallbacks           []Configuration     `toml:"fallbacks"`
+	FileCompress        bool                `toml:"file_compress"`
+	FileMaxAge          time.Duration       `toml:"file_max_age"`
+	FileMaxBackups      int                 `toml:"file_max_backups"`
+	FileMaxSize         int64               `toml:"file_max_size"`
+	FileSymlink         string              `toml:"file_symlink"`
+	Filename            string              `toml:"filename"`
+	FluentdAddress      string              `toml:"fluentd_address"`
+	FluentdRequireAck   bool                `toml:"fluentd_require_ack"`
+	Format              string              `toml:"format"`
+	GELFAddress         string              `toml:"gelf_address"`
+	GELFChunkSize       int                 `toml:"gelf_chunk_size"`
+	GELFCompression     string              `toml:"gelf_compression"`
+	GELFNetwork         string              `toml:"gelf_network"`
+	HTTPHeaders         map[string]string   `toml:"http_headers"`
+	Level               astikit.LoggerLevel `toml:"level"`
+	LokiLabels          []string            `toml:"loki_labels"`
+	LokiURL             string              `toml:"loki_url"`
+	MaxWriteLength      int                 `toml:"max_write_length"`
+	MessageKey          string              `toml:"message_key"`
+	NetBufferSize       int                 `toml:"net_buffer_size"`
+	NetStateHandler     NetStateHandler     `toml:"-"`
+	OTLPResource        map[string]string   `toml:"otlp_resource"`
+	OTLPURL             string              `toml:"otlp_url"`
+	Out                 string              `toml:"out"`
+	ReopenOnSIGHUP      bool                `toml:"reopen_on_sighup"`
+	RingBuffer          *RingBuffer         `toml:"-"`
+	Sinks               []Configuration     `toml:"sinks"`
+	Source              bool                `toml:"source"`
+	SyncTimeout         time.Duration       `toml:"sync_timeout"`
+	SyslogAddress       string              `toml:"syslog_address"`
+	SyslogFacility      string              `toml:"syslog_facility"`
+	SyslogNetwork       string              `toml:"syslog_network"`
+	SyslogTag           string              `toml:"syslog_tag"`
+	SyslogTLSConfig     *tls.Config         `toml:"-"`
+	Template            string              `toml:"template"`
+	TimestampFormat     string              `toml:"timestamp_format"`
+	WebhookEncoding     string              `toml:"webhook_encoding"`
+	WebhookGzip         bool                `toml:"webhook_gzip"`
+	WebhookSpillDir     string              `toml:"webhook_spill_dir"`
+	WebhookSpillMaxSize int                 `toml:"webhook_spill_max_size"`
+	WebhookURL          string              `toml:"webhook_url"`
 }
 
 // FlagConfig generates a Configuration based on flags
 func FlagConfig() (c Configuration) {
 	c = Configuration{
-		AppName:         *AppName,
-		Filename:        *Filename,
-		Format:          *Format,
-		Level:           astikit.LoggerLevelFromString(*Level),
-		MaxWriteLength:  *MaxWriteLength,
-		MessageKey:      *MessageKey,
-		Out:             *Out,
-		Source:          *Source,
-		TimestampFormat: *TimestampFormat,
+		AppName:             *AppName,
+		Async:               *Async,
+		AsyncOverflowPolicy: *AsyncOverflowPolicy,
+		AsyncQueueSize:      *AsyncQueueSize,
+		BatchBufferSize:     *BatchBufferSize,
+		BatchInterval:       *BatchInterval,
+		BatchMaxRetries:     *BatchMaxRetries,
+		BatchSize:           *BatchSize,
+		Color:               *Color,
+		ElasticsearchIndex:  *ElasticsearchIndex,
+		ElasticsearchURL:    *ElasticsearchURL,
+		FallbackInterval:    *FallbackInterval,
+		FileCompress:        *FileCompress,
+		FileMaxAge:          *FileMaxAge,
+		FileMaxBackups:      *FileMaxBackups,
+		FileMaxSize:         *FileMaxSize,
+		FileSymlink:         *FileSymlink,
+		Filename:            *Filename,
+		FluentdAddress:      *FluentdAddress,
+		FluentdRequireAck:   *FluentdRequireAck,
+		Format:              *Format,
+		GELFAddress:         *GELFAddress,
+		GELFChunkSize:       *GELFChunkSize,
+		GELFCompression:     *GELFCompression,
+		GELFNetwork:         *GELFNetwork,
+		Level:               astikit.LoggerLevelFromString(*Level),
+		LokiURL:             *LokiURL,
+		MaxWriteLength:      *MaxWriteLength,
+		MessageKey:          *MessageKey,
+		NetBufferSize:       *NetBufferSize,
+		OTLPURL:             *OTLPURL,
+		Out:                 *Out,
+		ReopenOnSIGHUP:      *ReopenOnSIGHUP,
+		Source:              *Source,
+		SyncTimeout:         *SyncTimeout,
+		SyslogAddress:       *SyslogAddress,
+		SyslogFacility:      *SyslogFacility,
+		SyslogNetwork:       *SyslogNetwork,
+		SyslogTag:           *SyslogTag,
+		Template:            *Template,
+		TimestampFormat:     *TimestampFormat,
+		WebhookEncoding:     *WebhookEncoding,
+		WebhookGzip:         *WebhookGzip,
+		WebhookSpillDir:     *WebhookSpillDir,
+		WebhookSpillMaxSize: *WebhookSpillMaxSize,
+		WebhookURL:          *WebhookURL,
 	}
 	if *Verbose {
 		c.Level = astikit.LoggerLevelDebug
@@ -7,7 +7,10 @@ import (
 
 type contextKey string
 
-const contextKeyFields contextKey = "astilog.fields"
+const (
+	contextKeyFields contextKey = "astilog.fields"
+	contextKeyTrace  contextKey = "astilog.trace"
+)
 
 type contextFields struct {
 	fs map[string]interface{}
@@ -66,3 +69,32 @@ func ContextWithFields(ctx context.Context, fs map[string]interface{}) context.C
 	}
 	return context.WithValue(ctx, contextKeyFields, cfs)
 }
+
+type contextTrace struct {
+	spanID  string
+	traceID string
+}
+
+// ContextWithTrace adds the hex encoded trace and span IDs to the context so that
+// outputs supporting them can link entries to traces
+func ContextWithTrace(ctx context.Context, traceID, spanID string) context.Context {
+	if ctx == nil {
+		return nil
+	}
+	return context.WithValue(ctx, contextKeyTrace, contextTrace{
+		spanID:  spanID,
+		traceID: traceID,
+	})
+}
+
+// TraceFromContext returns the trace and span IDs added to the context
+func TraceFromContext(ctx context.Context) (traceID, spanID string) {
+	if ctx == nil {
+		return
+	}
+	if v, ok := ctx.Value(contextKeyTrace).(contextTrace); ok {
+		traceID = v.traceID
+		spanID = v.spanID
+	}
+	return
+}
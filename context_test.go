@@ -30,3 +30,17 @@ func TestContext(t *testing.T) {
 		t.Errorf("expected %+v, got %+v", fs, g)
 	}
 }
+
+func TestContextTrace(t *testing.T) {
+	if traceID, spanID := TraceFromContext(context.Background()); traceID != "" || spanID != "" {
+		t.Errorf("expected empty ids, got %s and %s", traceID, spanID)
+	}
+	ctx := ContextWithTrace(context.Background(), "t", "s")
+	traceID, spanID := TraceFromContext(ctx)
+	if e, g := "t", traceID; e != g {
+		t.Errorf("expected %s, got %s", e, g)
+	}
+	if e, g := "s", spanID; e != g {
+		t.Errorf("expected %s, got %s", e, g)
+	}
+}
@@ -0,0 +1,141 @@
+package astilog
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const ecsVersion = "1.6.0"
+
+// ecsFormatter writes entries as Elastic Common Schema JSON documents. Objects are nested
+// based on dotted field keys. ECS fields are set first, and fields colliding with an
+// existing key are added to labels or dropped if they collide there as well.
+type ecsFormatter struct {
+	c Configuration
+}
+
+func newECSFormatter(c Configuration) *ecsFormatter {
+	return &ecsFormatter{c: c}
+}
+
+// Format implements the Formatter interface
+func (f *ecsFormatter) Format(e Entry) ([]byte, error) {
+	// Add base fields
+	m := ecsObject{
+		"@timestamp": e.Time.UTC().Format(time.RFC3339Nano),
+		"message":    e.Message,
+	}
+	ecsSet(m, "ecs.version", ecsVersion)
+	ecsSet(m, "log.level", e.Level.String())
+
+	// Add app name
+	if f.c.AppName != "" {
+		ecsSet(m, "service.name", f.c.AppName)
+	}
+
+	// Add source
+	if e.Source != "" {
+		if i := strings.LastIndex(e.Source, ":"); i >= 0 {
+			ecsSet(m, "log.origin.file.name", e.Source[:i])
+			if l, err := strconv.Atoi(e.Source[i+1:]); err == nil {
+				ecsSet(m, "log.origin.file.line", l)
+			}
+		} else {
+			ecsSet(m, "log.origin.file.name", e.Source)
+		}
+	}
+
+	// Sort fields
+	ks := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		// Source is added to log.origin
+		if k == "source" && e.Source != "" {
+			continue
+		}
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+
+	// The first error value is added to error.*
+	fek := ""
+	for _, k := range ks {
+		if err, ok := e.Fields[k].(error); ok {
+			fek = k
+			ecsSet(m, "error.message", err.Error())
+			ecsSet(m, "error.type", fmt.Sprintf("%T", err))
+			break
+		}
+	}
+
+	// Add fields
+	for _, k := range ks {
+		// First error has already been added
+		if k == fek {
+			continue
+		}
+
+		// Other errors are added as strings
+		v := e.Fields[k]
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+
+		// Field collides with an existing key
+		if !ecsSet(m, k, v) {
+			ecsSet(m, "labels."+strings.ReplaceAll(k, ".", "_"), v)
+		}
+	}
+
+	// Marshal
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling failed: %w", err)
+	}
+
+	// Add newline
+	b = append(b, newLine...)
+	return b, nil
+}
+
+// ecsObject is a nested object created by the formatter. Fields may be shared between
+// sinks and maps provided as values can't be modified, therefore only ecsObject values
+// are considered as objects.
+type ecsObject map[string]interface{}
+
+// ecsSet sets the value in nested objects based on the dotted key. It returns false and
+// doesn't set anything if the key is already used or if a parent key is used by a value
+// that is not an object.
+func ecsSet(m ecsObject, k string, v interface{}) bool {
+	// Loop through parent keys
+	ps := strings.Split(k, ".")
+	c := m
+	for _, p := range ps[:len(ps)-1] {
+		// Get child
+		i, ok := c[p]
+		if !ok {
+			n := make(ecsObject)
+			c[p] = n
+			c = n
+			continue
+		}
+
+		// Child is not an object
+		n, ok := i.(ecsObject)
+		if !ok {
+			return false
+		}
+		c = n
+	}
+
+	// Key is already used
+	l := ps[len(ps)-1]
+	if _, ok := c[l]; ok {
+		return false
+	}
+	c[l] = v
+	return true
+}
@@ -0,0 +1,63 @@
+package astilog
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astikit"
+)
+
+func TestECSFormatter(t *testing.T) {
+	// Create formatter
+	f := newECSFormatter(Configuration{AppName: "app", MessageKey: "ignored"})
+	fs := map[string]interface{}{
+		"err1":          errors.New("1"),
+		"err2":          errors.New("2"),
+		"http.method":   "GET",
+		"http.status":   200,
+		"k":             "v",
+		"log.level":     "ignored",
+		"map":           map[string]interface{}{"a": 1},
+		"map.b":         2,
+		"message":       "ignored",
+		"service":       "ignored",
+		"service.other": "v",
+		"source":        "main.go:12",
+	}
+
+	// Format
+	b, err := f.Format(Entry{
+		Fields:  fs,
+		Level:   astikit.LoggerLevelWarn,
+		Message: "msg",
+		Source:  "main.go:12",
+		Time:    time.Unix(5, 123).In(time.FixedZone("", 3600)),
+	})
+	if err != nil {
+		t.Fatal(fmt.Errorf("formatting failed: %w", err))
+	}
+	if e, g := `{"@timestamp":"1970-01-01T00:00:05.000000123Z","ecs":{"version":"1.6.0"},"err2":"2","error":{"message":"1","type":"*errors.errorString"},"http":{"method":"GET","status":200},"k":"v","labels":{"log_level":"ignored","map_b":2,"message":"ignored","service":"ignored"},"log":{"level":"warn","origin":{"file":{"line":12,"name":"main.go"}}},"map":{"a":1},"message":"msg","service":{"name":"app","other":"v"}}`+"\n", string(b); e != g {
+		t.Errorf("expected %s, got %s", e, g)
+	}
+
+	// Fields have not been modified
+	if e, g := 1, len(fs["map"].(map[string]interface{})); e != g {
+		t.Errorf("expected %d, got %d", e, g)
+	}
+
+	// Without source nor app name
+	f = newECSFormatter(Configuration{})
+	if b, err = f.Format(Entry{
+		Fields:  map[string]interface{}{"source": "v"},
+		Level:   astikit.LoggerLevelInfo,
+		Message: "msg",
+		Time:    time.Unix(5, 0),
+	}); err != nil {
+		t.Fatal(fmt.Errorf("formatting failed: %w", err))
+	}
+	if e, g := `{"@timestamp":"1970-01-01T00:00:05Z","ecs":{"version":"1.6.0"},"log":{"level":"info"},"message":"msg","source":"v"}`+"\n", string(b); e != g {
+		t.Errorf("expected %s, got %s", e, g)
+	}
+}
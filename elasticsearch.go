@@ -0,0 +1,184 @@
+package astilog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/asticode/go-astikit"
+)
+
+const (
+	elasticsearchDefaultIndex = "astilog-{2006.01.02}"
+	elasticsearchTimestampKey = "@timestamp"
+)
+
+// elasticsearchWriter sends entries in batches to the Elasticsearch or OpenSearch bulk
+// API. Only items that failed with a retryable status are retried.
+type elasticsearchWriter struct {
+	*batchWriter
+	c     Configuration
+	f     *jsonFormatter
+	hc    *httpClient
+	index string
+	url   string
+}
+
+func newElasticsearchWriter(c Configuration, createdAt time.Time) (w *elasticsearchWriter, err error) {
+	// Check url
+	if c.ElasticsearchURL == "" {
+		err = errors.New("elasticsearch url is empty")
+		return
+	}
+
+	// Create
+	w = &elasticsearchWriter{
+		c:     c,
+		f:     newJSONFormatter(c, createdAt),
+		hc:    newHTTPClient(c),
+		index: c.ElasticsearchIndex,
+		url:   strings.TrimRight(c.ElasticsearchURL, "/") + "/_bulk?filter_path=errors,items.*.status,items.*.error",
+	}
+
+	// Default index
+	if w.index == "" {
+		w.index = elasticsearchDefaultIndex
+	}
+
+	// Create batch writer
+	w.batchWriter = newBatchWriter(c, w.send, nil)
+	return
+}
+
+// Write implements the io.Writer interface
+func (w *elasticsearchWriter) Write(p []byte) (int, error) {
+	if err := w.writeEntry(entry{
+		l:   astikit.LoggerLevelInfo,
+		msg: string(bytes.TrimRight(p, "\n")),
+		t:   now(),
+	}, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// buildsPayload implements the payloadBuilder interface
+func (w *elasticsearchWriter) buildsPayload() bool {
+	return true
+}
+
+func (w *elasticsearchWriter) writeEntry(e entry, _ []byte) (err error) {
+	// Add timestamp to fields
+	fs := make(map[string]interface{}, len(e.fs)+1)
+	for k, v := range e.fs {
+		fs[k] = v
+	}
+	fs[elasticsearchTimestampKey] = e.t.UTC().Format(time.RFC3339Nano)
+
+	// Create action
+	var b []byte
+	if b, err = json.Marshal(map[string]interface{}{"index": map[string]string{"_index": renderFilename(w.index, e.t)}}); err != nil {
+		return fmt.Errorf("marshaling action failed: %w", err)
+	}
+	b = append(b, newLine...)
+
+	// Add document
+	fe := e.export()
+	fe.Fields = fs
+	var d []byte
+	if d, err = w.f.Format(fe); err != nil {
+		return fmt.Errorf("formatting failed: %w", err)
+	}
+	b = append(b, d...)
+	if !bytes.HasSuffix(b, newLine) {
+		b = append(b, newLine...)
+	}
+
+	// Add
+	return w.add(batchItem{
+		b: b,
+		e: e,
+	})
+}
+
+type elasticsearchBulkResponse struct {
+	Errors bool                                       `json:"errors"`
+	Items  []map[string]elasticsearchBulkResponseItem `json:"items"`
+}
+
+type elasticsearchBulkResponseItem struct {
+	Error  json.RawMessage `json:"error"`
+	Status int             `json:"status"`
+}
+
+func (w *elasticsearchWriter) send(is []batchItem) (retry, dropped []batchItem, err error) {
+	// Create body
+	buf := &bytes.Buffer{}
+	for _, i := range is {
+		buf.Write(i.b)
+	}
+
+	// Send
+	var b []byte
+	var retryable bool
+	if b, retryable, err = w.hc.do(http.MethodPost, w.url, "application/x-ndjson", buf.Bytes(), nil); err != nil {
+		if retryable {
+			retry = is
+		}
+		return
+	}
+
+	// Unmarshal
+	var r elasticsearchBulkResponse
+	if err = json.Unmarshal(b, &r); err != nil {
+		err = fmt.Errorf("unmarshaling failed: %w", err)
+		return
+	}
+
+	// No errors
+	if !r.Errors {
+		return
+	}
+
+	// Loop through items
+	var failed, retried []string
+	for idx, ri := range r.Items {
+		// Invalid index
+		if idx >= len(is) {
+			break
+		}
+
+		// Loop through actions
+		for _, a := range ri {
+			// Item succeeded
+			if a.Status >= 200 && a.Status <= 299 {
+				continue
+			}
+
+			// Item can be retried
+			m := fmt.Sprintf("status code %d: %s", a.Status, a.Error)
+			if (httpError{statusCode: a.Status}).retryable() {
+				retry = append(retry, is[idx])
+				retried = append(retried, m)
+			} else {
+				dropped = append(dropped, is[idx])
+				failed = append(failed, m)
+			}
+		}
+	}
+
+	// Items that can't be retried are dropped
+	if len(failed) > 0 {
+		handleError(w.c.ErrorHandler, fmt.Errorf("astilog: %d/%d elasticsearch items failed, first error is %s", len(failed), len(is), failed[0]))
+	}
+
+	// Items that can be retried
+	if len(retried) > 0 {
+		err = fmt.Errorf("%d/%d items failed, first error is %s", len(retried), len(is), retried[0])
+	}
+	return
+}
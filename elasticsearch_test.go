@@ -0,0 +1,147 @@
+package astilog
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astikit"
+)
+
+func TestElasticsearchWriter(t *testing.T) {
+	// Mock now
+	oldNow := now
+	defer func() { now = oldNow }()
+	n := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	now = func() time.Time { return n }
+
+	// Create server
+	var bodies []string
+	var paths []string
+	m := &sync.Mutex{}
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		m.Lock()
+		defer m.Unlock()
+
+		// Read body
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			rw.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		bodies = append(bodies, string(b))
+		paths = append(paths, r.URL.Path)
+
+		// First item is invalid and second item is throttled the first time
+		if len(bodies) == 1 {
+			rw.Write([]byte(`{"errors":true,"items":[{"index":{"status":400,"error":{"type":"mapper_parsing_exception"}}},{"index":{"status":429}},{"index":{"status":201}}]}`)) //nolint: errcheck
+			return
+		}
+		rw.Write([]byte(`{"errors":false,"items":[{"index":{"status":201}}]}`)) //nolint: errcheck
+	}))
+	defer s.Close()
+
+	// Create writer
+	w, err := newElasticsearchWriter(Configuration{
+		BatchInterval:      time.Hour,
+		ElasticsearchIndex: "logs-{2006.01.02}",
+		ElasticsearchURL:   s.URL + "/",
+		TimestampFormat:    "2006",
+	}, n)
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating writer failed: %w", err))
+	}
+	defer w.Close()
+
+	// Write
+	for _, msg := range []string{"msg1", "msg2", "msg3"} {
+		if err = w.writeEntry(entry{
+			fs:  map[string]interface{}{"k": "v"},
+			l:   astikit.LoggerLevelWarn,
+			msg: msg,
+			t:   n,
+		}, nil); err != nil {
+			t.Fatal(fmt.Errorf("writing failed: %w", err))
+		}
+	}
+
+	// Flush
+	if err = w.Flush(); err != nil {
+		t.Fatal(fmt.Errorf("flushing failed: %w", err))
+	}
+
+	// Assert
+	m.Lock()
+	defer m.Unlock()
+	if e, g := []string{"/_bulk", "/_bulk"}, paths; !reflect.DeepEqual(e, g) {
+		t.Errorf("expected %+v, got %+v", e, g)
+	}
+	item := func(msg string) string {
+		return `{"index":{"_index":"logs-2020.01.02"}}` + "\n" + `{"@timestamp":"2020-01-02T03:04:05Z","k":"v","level":"warn","msg":"` + msg + `","time":"2020"}` + "\n"
+	}
+	if e, g := []string{
+		item("msg1") + item("msg2") + item("msg3"),
+		item("msg2"),
+	}, bodies; !reflect.DeepEqual(e, g) {
+		t.Errorf("expected %s, got %s", strings.Join(e, "|"), strings.Join(g, "|"))
+	}
+	if e, g := uint64(1), w.Dropped(); e != g {
+		t.Errorf("expected %d, got %d", e, g)
+	}
+}
+
+func TestElasticsearchWriterLargeResponse(t *testing.T) {
+	// Create server
+	var query string
+	m := &sync.Mutex{}
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		m.Lock()
+		query = r.URL.RawQuery
+		m.Unlock()
+		rw.Write([]byte(`{"errors":false,"items":[{"index":{"status":201,"result":"` + strings.Repeat("a", 2<<20) + `"}}]}`)) //nolint: errcheck
+	}))
+	defer s.Close()
+
+	// Create writer
+	var errs []error
+	w, err := newElasticsearchWriter(Configuration{
+		BatchInterval:    time.Hour,
+		ElasticsearchURL: s.URL,
+		ErrorHandler:     func(err error) { errs = append(errs, err) },
+	}, time.Now())
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating writer failed: %w", err))
+	}
+	defer w.Close()
+
+	// Write
+	cs := &sinkCounters{}
+	if err = w.writeEntry(entry{cs: cs, msg: "msg"}, nil); err != nil {
+		t.Fatal(fmt.Errorf("writing failed: %w", err))
+	}
+	if err = w.Flush(); err != nil {
+		t.Fatal(fmt.Errorf("flushing failed: %w", err))
+	}
+
+	// Assert
+	m.Lock()
+	defer m.Unlock()
+	if e, g := "filter_path=errors,items.*.status,items.*.error", query; e != g {
+		t.Errorf("expected %s, got %s", e, g)
+	}
+	if e, g := 0, len(errs); e != g {
+		t.Errorf("expected %d, got %d: %v", e, g, errs)
+	}
+	if e, g := uint64(0), w.Dropped(); e != g {
+		t.Errorf("expected %d, got %d", e, g)
+	}
+	if e, g := uint64(1), cs.load().writes; e != g {
+		t.Errorf("expected %d, got %d", e, g)
+	}
+}
@@ -0,0 +1,310 @@
+package astilog
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/asticode/go-astikit"
+)
+
+const fallbackDefaultInterval = 30 * time.Second
+
+// FallbackEvent is emitted when a sink switches to another output of its fallback chain
+type FallbackEvent struct {
+	// Err is the error that caused the switch. It's nil when recovering.
+	Err       error
+	From      string
+	Recovered bool
+	To        string
+}
+
+// FallbackHandler handles fallback events
+type FallbackHandler func(e FallbackEvent)
+
+// fallbackWriter writes to the first output of its chain that works. The chain is made
+// of the primary output followed by the fallbacks. When the current output fails, the
+// next one is used and, while not writing to the primary output, it tries to switch
+// back to it at regular intervals.
+type fallbackWriter struct {
+	cs             []Configuration
+	createdAt      time.Time
+	current        int
+	dropped        uint64 // Entries dropped by outputs that have been closed
+	errorHandler   ErrorHandler
+	handler        FallbackHandler
+	lastRecovery   time.Time
+	m              *sync.Mutex // Locks current, dropped, lastRecovery and ws
+	maxWriteLength int
+	interval       time.Duration
+	ws             []io.WriteCloser // Nil when the output is not opened
+}
+
+func newFallbackWriter(c Configuration, createdAt time.Time) (w *fallbackWriter) {
+	// Create
+	w = &fallbackWriter{
+		createdAt:      createdAt,
+		errorHandler:   c.ErrorHandler,
+		handler:        c.FallbackHandler,
+		m:              &sync.Mutex{},
+		maxWriteLength: c.MaxWriteLength,
+		interval:       c.FallbackInterval,
+	}
+
+	// Default recovery interval
+	if w.interval <= 0 {
+		w.interval = fallbackDefaultInterval
+	}
+
+	// Create chain
+	pc := c
+	pc.Fallbacks = nil
+	w.cs = append(w.cs, pc)
+	for _, fc := range c.Fallbacks {
+		fc = sinkConfiguration(c, fc)
+		fc.Fallbacks = nil
+		w.cs = append(w.cs, fc)
+	}
+	w.ws = make([]io.WriteCloser, len(w.cs))
+
+	// Open the first output that works
+	w.current = -1
+	var err error
+	for idx := range w.cs {
+		errOpen := w.open(idx)
+		if errOpen == nil {
+			w.switchTo(idx, err)
+			return
+		}
+		err = fmt.Errorf("opening %s failed: %w", outputName(w.cs[idx]), errOpen)
+	}
+
+	// Default is stdout
+	w.cs = append(w.cs, Configuration{Out: OutStdout})
+	w.ws = append(w.ws, astikit.NopCloser(os.Stdout))
+	w.switchTo(len(w.cs)-1, err)
+	return
+}
+
+// open opens the output if needed. It assumes the lock is held.
+func (w *fallbackWriter) open(idx int) (err error) {
+	// Already opened
+	if w.ws[idx] != nil {
+		return
+	}
+
+	// Open
+	if w.ws[idx], err = newWriter(w.cs[idx], w.createdAt); err != nil {
+		w.ws[idx] = nil
+		return
+	}
+	return
+}
+
+// close closes the output. It assumes the lock is held.
+func (w *fallbackWriter) close(idx int) {
+	if w.ws[idx] == nil {
+		return
+	}
+	if err := w.ws[idx].Close(); err != nil {
+		handleError(w.errorHandler, fmt.Errorf("astilog: closing %s failed: %w", outputName(w.cs[idx]), err))
+	}
+	w.closed(idx)
+}
+
+// closed keeps the count of entries dropped by the output once it has been closed. It
+// assumes the lock is held.
+func (w *fallbackWriter) closed(idx int) {
+	if d, ok := w.ws[idx].(dropper); ok {
+		w.dropped += d.Dropped()
+	}
+	w.ws[idx] = nil
+}
+
+// switchTo updates the current output and emits an event. It assumes the lock is held.
+func (w *fallbackWriter) switchTo(idx int, err error) {
+	// Nothing to do
+	if idx == w.current {
+		return
+	}
+
+	// Create event
+	e := FallbackEvent{
+		Err:       err,
+		Recovered: idx == 0 && w.current > 0,
+		To:        outputName(w.cs[idx]),
+	}
+	if w.current >= 0 {
+		e.From = outputName(w.cs[w.current])
+	} else {
+		e.From = outputName(w.cs[0])
+	}
+
+	// Update current
+	w.current = idx
+
+	// Primary output is used
+	if e.From == e.To && err == nil {
+		return
+	}
+
+	// Handle event
+	if w.handler != nil {
+		w.handler(e)
+	} else if !e.Recovered {
+		handleError(w.errorHandler, fmt.Errorf("astilog: switched from %s to %s: %w", e.From, e.To, e.Err))
+	}
+}
+
+// Write implements the io.Writer interface
+func (w *fallbackWriter) Write(p []byte) (int, error) {
+	if err := w.write(func(wr io.Writer) error {
+		_, err := wr.Write(p)
+		return err
+	}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *fallbackWriter) writeEntry(e entry, b []byte) error {
+	return w.write(func(wr io.Writer) error { return writeEntry(wr, e, b, w.maxWriteLength) })
+}
+
+func (w *fallbackWriter) write(fn func(w io.Writer) error) (err error) {
+	// Lock
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	// Try to recover
+	if w.current > 0 && now().Sub(w.lastRecovery) >= w.interval {
+		// Update last recovery
+		w.lastRecovery = now()
+
+		// Write to primary output
+		if errOpen := w.open(0); errOpen == nil {
+			if errWrite := fn(w.ws[0]); errWrite == nil {
+				w.switchTo(0, nil)
+				return
+			}
+			w.close(0)
+		}
+	}
+
+	// Loop through outputs
+	for idx := w.current; idx < len(w.cs); idx++ {
+		// Open
+		if errOpen := w.open(idx); errOpen != nil {
+			err = fmt.Errorf("opening %s failed: %w", outputName(w.cs[idx]), errOpen)
+			continue
+		}
+
+		// Write
+		if errWrite := fn(w.ws[idx]); errWrite != nil {
+			err = fmt.Errorf("writing to %s failed: %w", outputName(w.cs[idx]), errWrite)
+			w.close(idx)
+			continue
+		}
+
+		// Switch
+		if idx != w.current {
+			w.lastRecovery = now()
+			w.switchTo(idx, err)
+		}
+		err = nil
+		return
+	}
+	if err == nil {
+		err = errors.New("no output available")
+	}
+	return
+}
+
+// Close implements the io.Closer interface
+func (w *fallbackWriter) Close() error {
+	w.m.Lock()
+	defer w.m.Unlock()
+	errs := astikit.NewErrors()
+	for idx, wr := range w.ws {
+		if wr != nil {
+			errs.Add(wr.Close())
+			w.closed(idx)
+		}
+	}
+	if errs.IsNil() {
+		return nil
+	}
+	return errs
+}
+
+// opened returns the outputs that are opened
+func (w *fallbackWriter) opened() (ws []io.WriteCloser) {
+	w.m.Lock()
+	defer w.m.Unlock()
+	for _, wr := range w.ws {
+		if wr != nil {
+			ws = append(ws, wr)
+		}
+	}
+	return
+}
+
+// Flush implements the flusher interface
+func (w *fallbackWriter) Flush() error {
+	errs := astikit.NewErrors()
+	for _, wr := range w.opened() {
+		if f, ok := wr.(flusher); ok {
+			errs.Add(f.Flush())
+		}
+	}
+	if errs.IsNil() {
+		return nil
+	}
+	return errs
+}
+
+// Sync implements the syncer interface
+func (w *fallbackWriter) Sync() error {
+	errs := astikit.NewErrors()
+	for _, wr := range w.opened() {
+		if s, ok := wr.(syncer); ok {
+			errs.Add(s.Sync())
+		} else if f, ok := wr.(flusher); ok {
+			errs.Add(f.Flush())
+		}
+	}
+	if errs.IsNil() {
+		return nil
+	}
+	return errs
+}
+
+// Reopen implements the reopener interface
+func (w *fallbackWriter) Reopen() error {
+	errs := astikit.NewErrors()
+	for _, wr := range w.opened() {
+		if r, ok := wr.(reopener); ok {
+			errs.Add(r.Reopen())
+		}
+	}
+	if errs.IsNil() {
+		return nil
+	}
+	return errs
+}
+
+// Dropped implements the dropper interface
+func (w *fallbackWriter) Dropped() (n uint64) {
+	w.m.Lock()
+	defer w.m.Unlock()
+	n = w.dropped
+	for _, wr := range w.ws {
+		if d, ok := wr.(dropper); ok {
+			n += d.Dropped()
+		}
+	}
+	return
+}
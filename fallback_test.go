@@ -0,0 +1,195 @@
+package astilog
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astikit"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, errors.New("failed") }
+
+func TestFallbackWriter(t *testing.T) {
+	// Mock now
+	oldNow := now
+	defer func() { now = oldNow }()
+	n := time.Unix(0, 0)
+	now = func() time.Time { return n }
+
+	// Create temp dir
+	d, err := ioutil.TempDir("", "astilog_")
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating temp dir failed: %w", err))
+	}
+	defer os.RemoveAll(d)
+
+	// Create writer
+	var es []FallbackEvent
+	p1 := filepath.Join(d, "missing", "primary.log")
+	p2 := filepath.Join(d, "fallback.log")
+	w := newFallbackWriter(Configuration{
+		FallbackHandler:  func(e FallbackEvent) { es = append(es, e) },
+		FallbackInterval: time.Minute,
+		Fallbacks:        []Configuration{{Filename: p2}, {Out: OutStderr}},
+		Filename:         p1,
+	}, n)
+	defer w.Close()
+
+	// Primary output failed to open
+	if e, g := 1, w.current; e != g {
+		t.Errorf("expected %d, got %d", e, g)
+	}
+	if e, g := 1, len(es); e != g {
+		t.Fatalf("expected %d, got %d", e, g)
+	}
+	if es[0].From != p1 || es[0].To != p2 || es[0].Err == nil || es[0].Recovered {
+		t.Errorf("invalid event %+v", es[0])
+	}
+
+	// Write to fallback
+	if _, err = w.Write([]byte("1\n")); err != nil {
+		t.Fatal(fmt.Errorf("writing failed: %w", err))
+	}
+
+	// Primary output is not retried before the interval
+	if err = os.MkdirAll(filepath.Dir(p1), 0755); err != nil {
+		t.Fatal(fmt.Errorf("creating dir failed: %w", err))
+	}
+	n = n.Add(30 * time.Second)
+	if err = w.writeEntry(entry{}, []byte("2\n")); err != nil {
+		t.Fatal(fmt.Errorf("writing failed: %w", err))
+	}
+
+	// Primary output is recovered
+	n = n.Add(time.Minute)
+	if _, err = w.Write([]byte("3\n")); err != nil {
+		t.Fatal(fmt.Errorf("writing failed: %w", err))
+	}
+	if e, g := 2, len(es); e != g {
+		t.Fatalf("expected %d, got %d", e, g)
+	}
+	if es[1].From != p2 || es[1].To != p1 || es[1].Err != nil || !es[1].Recovered {
+		t.Errorf("invalid event %+v", es[1])
+	}
+
+	// Primary output fails at runtime
+	w.m.Lock()
+	w.ws[0].Close()
+	w.ws[0] = mockedCloser{Writer: failingWriter{}}
+	w.m.Unlock()
+	if _, err = w.Write([]byte("4\n")); err != nil {
+		t.Fatal(fmt.Errorf("writing failed: %w", err))
+	}
+	if e, g := 3, len(es); e != g {
+		t.Fatalf("expected %d, got %d", e, g)
+	}
+	if es[2].From != p1 || es[2].To != p2 || es[2].Err == nil || es[2].Recovered {
+		t.Errorf("invalid event %+v", es[2])
+	}
+
+	// Assert
+	for p, e := range map[string]string{p1: "3\n", p2: "1\n2\n4\n"} {
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			t.Fatal(fmt.Errorf("reading %s failed: %w", p, err))
+		}
+		if g := string(b); e != g {
+			t.Errorf("expected %s, got %s", e, g)
+		}
+	}
+}
+
+func TestFallbackWriterStdout(t *testing.T) {
+	w := newFallbackWriter(Configuration{
+		FallbackHandler: func(e FallbackEvent) {},
+		Fallbacks:       []Configuration{{Filename: "testdata/invalidpath/fallback.log"}},
+		Filename:        "testdata/invalidpath/primary.log",
+	}, time.Now())
+	defer w.Close()
+	if e, g := astikit.NopCloser(os.Stdout), w.ws[w.current]; e != g {
+		t.Errorf("expected %v, got %v", e, g)
+	}
+}
+
+func TestFallbackWriterErrorHandler(t *testing.T) {
+	// Mock now
+	oldNow := now
+	defer func() { now = oldNow }()
+	n := time.Unix(0, 0)
+	now = func() time.Time { return n }
+
+	// Create temp dir
+	d, err := ioutil.TempDir("", "astilog_")
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating temp dir failed: %w", err))
+	}
+	defer os.RemoveAll(d)
+
+	// Create writer
+	var errs []error
+	p1 := filepath.Join(d, "missing", "primary.log")
+	w := newFallbackWriter(Configuration{
+		ErrorHandler:     func(err error) { errs = append(errs, err) },
+		FallbackInterval: time.Minute,
+		Fallbacks:        []Configuration{{Filename: filepath.Join(d, "fallback.log")}},
+		Filename:         p1,
+	}, n)
+	defer w.Close()
+
+	// Switching to the fallback is reported
+	if e, g := 1, len(errs); e != g {
+		t.Fatalf("expected %d, got %d", e, g)
+	}
+
+	// Switching back is not reported
+	if err = os.MkdirAll(filepath.Dir(p1), 0755); err != nil {
+		t.Fatal(fmt.Errorf("creating dir failed: %w", err))
+	}
+	n = n.Add(2 * time.Minute)
+	if _, err = w.Write([]byte("1\n")); err != nil {
+		t.Fatal(fmt.Errorf("writing failed: %w", err))
+	}
+	if e, g := 0, w.current; e != g {
+		t.Errorf("expected %d, got %d", e, g)
+	}
+	if e, g := 1, len(errs); e != g {
+		t.Errorf("expected %d, got %d", e, g)
+	}
+}
+
+func TestFallbackWriterDropped(t *testing.T) {
+	// Create writer
+	w := newFallbackWriter(Configuration{
+		FallbackHandler: func(e FallbackEvent) {},
+		Fallbacks:       []Configuration{{Out: OutStderr}},
+		Out:             OutStdout,
+	}, time.Now())
+	w.ws[0] = mockedDropper{WriteCloser: mockedCloser{Writer: failingWriter{}}, dropped: 2}
+	w.ws[1] = mockedDropper{WriteCloser: astikit.NopCloser(ioutil.Discard), dropped: 3}
+
+	// Primary output is closed once writing to it failed
+	if _, err := w.Write([]byte("1\n")); err != nil {
+		t.Fatal(fmt.Errorf("writing failed: %w", err))
+	}
+	if w.ws[0] != nil {
+		t.Error("expected primary output to be closed")
+	}
+	if e, g := uint64(5), w.Dropped(); e != g {
+		t.Errorf("expected %d, got %d", e, g)
+	}
+
+	// Counts are kept once outputs are closed
+	if err := w.Close(); err != nil {
+		t.Fatal(fmt.Errorf("closing failed: %w", err))
+	}
+	if e, g := uint64(5), w.Dropped(); e != g {
+		t.Errorf("expected %d, got %d", e, g)
+	}
+}
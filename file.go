@@ -0,0 +1,598 @@
+package astilog
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asticode/go-astikit"
+)
+
+// fileWriter writes to a file and rotates it once it has reached its max size or
+// once the time period of its filename template has changed. Old files are compressed
+// and removed in the background.
+type fileWriter struct {
+	c       Configuration
+	closed  bool
+	f       *os.File
+	lastIdx int
+	m       *sync.Mutex // Locks closed, f, lastIdx, path and size
+	mh      *sync.Mutex // Makes sure housekeeping is not done concurrently
+	path    string
+	size    int64
+	wg      *sync.WaitGroup
+}
+
+func newFileWriter(c Configuration) (w *fileWriter, err error) {
+	// Create
+	w = &fileWriter{
+		c:  c,
+		m:  &sync.Mutex{},
+		mh: &sync.Mutex{},
+		wg: &sync.WaitGroup{},
+	}
+
+	// Switch
+	if err = w.switchPath(renderFilename(c.Filename, now())); err != nil {
+		return
+	}
+	return
+}
+
+// renderFilename replaces every "{<layout>}" occurrence in the filename with the time
+// formatted with the GO time layout
+func renderFilename(filename string, t time.Time) string {
+	var b strings.Builder
+	for {
+		// Get boundaries
+		start := strings.Index(filename, "{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(filename[start:], "}")
+		if end < 0 {
+			break
+		}
+
+		// Format
+		b.WriteString(filename[:start])
+		b.WriteString(t.Format(filename[start+1 : start+end]))
+		filename = filename[start+end+1:]
+	}
+	b.WriteString(filename)
+	return b.String()
+}
+
+// switchPath closes the current file if any, and opens the new path
+func (w *fileWriter) switchPath(path string) (err error) {
+	// Close
+	var errClose error
+	if w.f != nil {
+		errClose = w.f.Close()
+		w.f = nil
+	}
+
+	// Path and last index are only updated once the file has been opened so that switching
+	// is retried on the next write
+	oldPath, oldLastIdx := w.path, w.lastIdx
+	defer func() {
+		if w.f == nil {
+			w.path, w.lastIdx = oldPath, oldLastIdx
+		}
+	}()
+	w.path = path
+
+	// Get last backup index
+	bs, errBackups := w.backups()
+	if errBackups != nil {
+		return fmt.Errorf("listing backups failed: %w", errBackups)
+	}
+	w.lastIdx = 0
+	if len(bs) > 0 {
+		w.lastIdx = bs[len(bs)-1].idx
+	}
+
+	// Open
+	if errOpen := w.open(); errOpen != nil {
+		return errOpen
+	}
+
+	// The new file is used, closing the old one failing shouldn't fail the write
+	if errClose != nil {
+		handleError(w.c.ErrorHandler, fmt.Errorf("astilog: closing %s failed: %w", oldPath, errClose))
+	}
+
+	// Housekeep
+	w.housekeepInBackground()
+	return
+}
+
+func (w *fileWriter) open() (err error) {
+	// Open file
+	if w.f, err = os.OpenFile(w.path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0755); err != nil {
+		w.f = nil
+		err = fmt.Errorf("opening %s failed: %w", w.path, err)
+		return
+	}
+
+	// Get size
+	var fi os.FileInfo
+	if fi, err = w.f.Stat(); err != nil {
+		err = fmt.Errorf("stating %s failed: %w", w.path, err)
+		return
+	}
+	w.size = fi.Size()
+
+	// Update symlink
+	if w.c.FileSymlink != "" {
+		if err = w.symlink(); err != nil {
+			err = fmt.Errorf("updating symlink failed: %w", err)
+			return
+		}
+	}
+	return
+}
+
+// symlink makes sure the symlink is replaced atomically
+func (w *fileWriter) symlink() (err error) {
+	// Get absolute path
+	var p string
+	if p, err = filepath.Abs(w.path); err != nil {
+		err = fmt.Errorf("getting absolute path of %s failed: %w", w.path, err)
+		return
+	}
+
+	// Create tmp symlink
+	tmp := w.c.FileSymlink + ".tmp"
+	os.Remove(tmp) //nolint: errcheck
+	if err = os.Symlink(p, tmp); err != nil {
+		err = fmt.Errorf("creating symlink %s failed: %w", tmp, err)
+		return
+	}
+
+	// Rename
+	if err = os.Rename(tmp, w.c.FileSymlink); err != nil {
+		err = fmt.Errorf("renaming %s failed: %w", tmp, err)
+		return
+	}
+	return
+}
+
+// Close implements the io.Closer interface
+func (w *fileWriter) Close() (err error) {
+	// Make sure to wait for housekeeping
+	defer w.wg.Wait()
+
+	// Lock
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	// Close
+	w.closed = true
+	if w.f == nil {
+		return
+	}
+	err = w.f.Close()
+	w.f = nil
+	return
+}
+
+// Sync commits the current file to stable storage
+func (w *fileWriter) Sync() error {
+	w.m.Lock()
+	defer w.m.Unlock()
+	if w.f == nil {
+		return nil
+	}
+	return w.f.Sync()
+}
+
+// Reopen closes the current file and opens the filename again which is useful when
+// the file has been moved by an external tool such as logrotate
+func (w *fileWriter) Reopen() error {
+	w.m.Lock()
+	defer w.m.Unlock()
+	if w.closed {
+		return nil
+	}
+	return w.switchPath(renderFilename(w.c.Filename, now()))
+}
+
+// Write implements the io.Writer interface
+func (w *fileWriter) Write(p []byte) (n int, err error) {
+	// Lock
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	// Writer is closed
+	if w.closed {
+		err = fmt.Errorf("%s is closed", w.path)
+		return
+	}
+
+	// Time period has changed
+	if p := renderFilename(w.c.Filename, now()); p != w.path {
+		if err = w.switchPath(p); err != nil && w.f == nil {
+			return
+		}
+	}
+
+	// Rotate
+	if w.f != nil && w.c.FileMaxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.c.FileMaxSize {
+		if err = w.rotate(); err != nil && w.f == nil {
+			return
+		}
+	}
+
+	// Opening failed previously
+	if w.f == nil {
+		if err = w.open(); err != nil {
+			return
+		}
+	}
+
+	// Write
+	var errWrite error
+	n, errWrite = w.f.Write(p)
+	w.size += int64(n)
+	if errWrite != nil {
+		err = errWrite
+	}
+	return
+}
+
+// rotate always tries to reopen the file so that entries are never lost even if
+// renaming failed
+func (w *fileWriter) rotate() (err error) {
+	// Close
+	if err = w.f.Close(); err != nil {
+		err = fmt.Errorf("closing %s failed: %w", w.path, err)
+	}
+	w.f = nil
+
+	// Rename
+	if err == nil {
+		w.lastIdx++
+		if err = os.Rename(w.path, w.backupPath(w.lastIdx)); err != nil {
+			err = fmt.Errorf("renaming %s failed: %w", w.path, err)
+		}
+	}
+
+	// Open
+	if errOpen := w.open(); errOpen != nil {
+		w.f = nil
+		err = errOpen
+		return
+	}
+
+	// Housekeep
+	w.housekeepInBackground()
+	return
+}
+
+func (w *fileWriter) backupPath(idx int) string {
+	return w.path + "." + strconv.Itoa(idx)
+}
+
+type fileBackup struct {
+	idx  int
+	path string
+}
+
+// backups returns the backups sorted from the oldest to the newest
+func (w *fileWriter) backups() (bs []fileBackup, err error) {
+	// Glob
+	var ps []string
+	if ps, err = filepath.Glob(w.path + ".*"); err != nil {
+		return
+	}
+
+	// Loop through paths
+	for _, p := range ps {
+		// Parse index
+		idx := backupIndex(strings.TrimPrefix(p, w.path))
+		if idx <= 0 {
+			continue
+		}
+
+		// Append
+		bs = append(bs, fileBackup{
+			idx:  idx,
+			path: p,
+		})
+	}
+
+	// Sort
+	sort.Slice(bs, func(i, j int) bool { return bs[i].idx < bs[j].idx })
+	return
+}
+
+// backupIndex parses suffixes such as ".<n>" or ".<n>.gz" and returns 0 if there's
+// no index
+func backupIndex(suffix string) int {
+	suffix = strings.TrimSuffix(suffix, ".gz")
+	if !strings.HasPrefix(suffix, ".") {
+		return 0
+	}
+	idx, err := strconv.Atoi(suffix[1:])
+	if err != nil || idx < 0 {
+		return 0
+	}
+	return idx
+}
+
+func (w *fileWriter) housekeepInBackground() {
+	// Nothing to do
+	if !w.c.FileCompress && w.c.FileMaxAge <= 0 && w.c.FileMaxBackups <= 0 {
+		return
+	}
+
+	// Housekeep
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		if err := w.housekeep(); err != nil {
+			handleError(w.c.ErrorHandler, fmt.Errorf("astilog: housekeeping failed: %w", err))
+		}
+	}()
+}
+
+type fileOld struct {
+	idx     int
+	modTime time.Time
+	path    string
+}
+
+// housekeep removes old files that exceed max backups or max age, and compresses the
+// remaining ones. Old files are files matching the filename template or backups.
+func (w *fileWriter) housekeep() (err error) {
+	// Lock
+	w.mh.Lock()
+	defer w.mh.Unlock()
+
+	// Get old files
+	var fos []fileOld
+	if fos, err = w.olds(); err != nil {
+		err = fmt.Errorf("listing old files failed: %w", err)
+		return
+	}
+
+	// Loop through old files
+	errs := astikit.NewErrors()
+	for idx, fo := range fos {
+		// Remove
+		if (w.c.FileMaxBackups > 0 && idx >= w.c.FileMaxBackups) ||
+			(w.c.FileMaxAge > 0 && now().Sub(fo.modTime) > w.c.FileMaxAge) {
+			if err = os.Remove(fo.path); err != nil && !os.IsNotExist(err) {
+				errs.Add(fmt.Errorf("removing %s failed: %w", fo.path, err))
+			}
+			continue
+		}
+
+		// Compress
+		if w.c.FileCompress && !strings.HasSuffix(fo.path, ".gz") {
+			if err = compressFile(fo.path, fo.modTime); err != nil {
+				errs.Add(fmt.Errorf("compressing %s failed: %w", fo.path, err))
+			}
+		}
+	}
+
+	// Errors
+	if !errs.IsNil() {
+		return errs
+	}
+	return nil
+}
+
+// olds returns old files sorted from the newest to the oldest
+func (w *fileWriter) olds() (fos []fileOld, err error) {
+	// Get current path
+	w.m.Lock()
+	current := w.path
+	w.m.Unlock()
+
+	// Replace time layouts with wildcards
+	pattern := w.c.Filename
+	for {
+		start := strings.Index(pattern, "{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(pattern[start:], "}")
+		if end < 0 {
+			break
+		}
+		pattern = pattern[:start] + "*" + pattern[start+end+1:]
+	}
+
+	// Create matcher
+	m := newFilenameMatcher(w.c.Filename)
+
+	// Glob
+	var ps1, ps2 []string
+	if ps1, err = filepath.Glob(pattern); err != nil {
+		err = fmt.Errorf("globbing %s failed: %w", pattern, err)
+		return
+	}
+	if ps2, err = filepath.Glob(pattern + ".*"); err != nil {
+		err = fmt.Errorf("globbing %s.* failed: %w", pattern, err)
+		return
+	}
+
+	// Loop through paths
+	for _, p := range append(ps1, ps2...) {
+		// Path should be ignored. Globbed paths may be cleaned.
+		if cp := filepath.Clean(p); cp == filepath.Clean(current) || (w.c.FileSymlink != "" && (cp == filepath.Clean(w.c.FileSymlink) || cp == filepath.Clean(w.c.FileSymlink+".tmp"))) {
+			continue
+		}
+
+		// Path has not been created by the writer
+		if !m.matchWithBackup(p) {
+			continue
+		}
+
+		// Stat
+		fi, errStat := os.Lstat(p)
+		if errStat != nil || !fi.Mode().IsRegular() {
+			continue
+		}
+
+		// Get index
+		var idx int
+		if i := strings.LastIndex(strings.TrimSuffix(p, ".gz"), "."); i >= 0 {
+			idx = backupIndex(p[i:])
+		}
+
+		// Append
+		fos = append(fos, fileOld{
+			idx:     idx,
+			modTime: fi.ModTime(),
+			path:    p,
+		})
+	}
+
+	// Sort
+	sort.Slice(fos, func(i, j int) bool {
+		if fos[i].modTime.Equal(fos[j].modTime) {
+			return fos[i].idx > fos[j].idx
+		}
+		return fos[i].modTime.After(fos[j].modTime)
+	})
+	return
+}
+
+// filenameMatcher checks whether paths match the filename template, in which case they
+// have been created by the writer
+type filenameMatcher struct {
+	layouts []string
+	r       *regexp.Regexp
+}
+
+func newFilenameMatcher(filename string) (m *filenameMatcher) {
+	// Create
+	m = &filenameMatcher{}
+
+	// Globbed paths are cleaned
+	filename = filepath.Clean(filename)
+
+	// Loop through time layouts
+	var b strings.Builder
+	b.WriteString("^")
+	for {
+		// Get boundaries
+		start := strings.Index(filename, "{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(filename[start:], "}")
+		if end < 0 {
+			break
+		}
+
+		// Add layout
+		b.WriteString(regexp.QuoteMeta(filename[:start]))
+		b.WriteString("(.+)")
+		m.layouts = append(m.layouts, filename[start+1:start+end])
+		filename = filename[start+end+1:]
+	}
+	b.WriteString(regexp.QuoteMeta(filename))
+	b.WriteString("$")
+
+	// Compile
+	m.r = regexp.MustCompile(b.String())
+	return
+}
+
+// match checks whether the path is the filename template formatted with a time
+func (m *filenameMatcher) match(p string) bool {
+	// Match
+	ss := m.r.FindStringSubmatch(filepath.Clean(p))
+	if ss == nil {
+		return false
+	}
+
+	// Time layouts must parse back
+	for i, l := range m.layouts {
+		if _, err := time.Parse(l, ss[i+1]); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// matchWithBackup checks whether the path matches the filename template, or is a backup of
+// it, optionally compressed
+func (m *filenameMatcher) matchWithBackup(p string) bool {
+	// Remove compression extension
+	p = strings.TrimSuffix(p, ".gz")
+
+	// Path matches the filename template
+	if m.match(p) {
+		return true
+	}
+
+	// Path is a backup
+	if i := strings.LastIndex(p, "."); i >= 0 && backupIndex(p[i:]) > 0 {
+		return m.match(p[:i])
+	}
+	return false
+}
+
+func compressFile(src string, modTime time.Time) (err error) {
+	// Open src
+	var fs *os.File
+	if fs, err = os.Open(src); err != nil {
+		err = fmt.Errorf("opening %s failed: %w", src, err)
+		return
+	}
+	defer fs.Close()
+
+	// Create dst
+	dst := src + ".gz"
+	var fd *os.File
+	if fd, err = os.OpenFile(dst, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0755); err != nil {
+		err = fmt.Errorf("creating %s failed: %w", dst, err)
+		return
+	}
+
+	// Compress
+	gw := gzip.NewWriter(fd)
+	if _, err = io.Copy(gw, fs); err != nil {
+		fd.Close()
+		err = fmt.Errorf("copying failed: %w", err)
+		return
+	}
+
+	// Close
+	if err = gw.Close(); err != nil {
+		fd.Close()
+		err = fmt.Errorf("closing gzip writer failed: %w", err)
+		return
+	}
+	if err = fd.Close(); err != nil {
+		err = fmt.Errorf("closing %s failed: %w", dst, err)
+		return
+	}
+
+	// Keep mod time so that retention is not impacted
+	if err = os.Chtimes(dst, modTime, modTime); err != nil {
+		err = fmt.Errorf("updating %s times failed: %w", dst, err)
+		return
+	}
+
+	// Remove src
+	fs.Close()
+	if err = os.Remove(src); err != nil {
+		err = fmt.Errorf("removing %s failed: %w", src, err)
+		return
+	}
+	return
+}
@@ -0,0 +1,654 @@
+package astilog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileWriter(t *testing.T) {
+	// Create temp dir
+	d, err := ioutil.TempDir("", "astilog_")
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating temp dir failed: %w", err))
+	}
+
+	// Make sure to delete directory
+	defer os.RemoveAll(d)
+
+	// Create writer
+	p := filepath.Join(d, "f.log")
+	w, err := newFileWriter(Configuration{
+		FileMaxBackups: 2,
+		FileMaxSize:    10,
+		Filename:       p,
+	})
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating file writer failed: %w", err))
+	}
+	defer w.Close()
+
+	// Assert helper
+	assertFile := func(p, e string) {
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			t.Fatal(fmt.Errorf("reading %s failed: %w", p, err))
+		}
+		if !bytes.Equal([]byte(e), b) {
+			t.Errorf("expected %s, got %s", e, b)
+		}
+	}
+
+	// Size is not reached
+	w.Write([]byte("111\n")) //nolint: errcheck
+	w.Write([]byte("222\n")) //nolint: errcheck
+	assertFile(p, "111\n222\n")
+
+	// Size is reached
+	w.Write([]byte("333\n")) //nolint: errcheck
+	assertFile(p, "333\n")
+	assertFile(p+".1", "111\n222\n")
+
+	// Entries bigger than max size are not split
+	w.Write([]byte("4444444444444\n")) //nolint: errcheck
+	assertFile(p, "4444444444444\n")
+	assertFile(p+".2", "333\n")
+
+	// Max backups is reached
+	w.Write([]byte("555\n")) //nolint: errcheck
+	w.wg.Wait()
+	assertFile(p, "555\n")
+	assertFile(p+".3", "4444444444444\n")
+	assertFile(p+".2", "333\n")
+	if _, err := os.Stat(p + ".1"); !os.IsNotExist(err) {
+		t.Errorf("expected %s to not exist", p+".1")
+	}
+
+	// Backup indexes are retrieved on open
+	w.Close()
+	w, err = newFileWriter(Configuration{
+		FileMaxBackups: 2,
+		FileMaxSize:    10,
+		Filename:       p,
+	})
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating file writer failed: %w", err))
+	}
+	w.Write([]byte("666666\n")) //nolint: errcheck
+	assertFile(p, "666666\n")
+	assertFile(p+".4", "555\n")
+}
+
+func TestFileWriterConcurrency(t *testing.T) {
+	// Create temp dir
+	d, err := ioutil.TempDir("", "astilog_")
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating temp dir failed: %w", err))
+	}
+
+	// Make sure to delete directory
+	defer os.RemoveAll(d)
+
+	// Create writer
+	p := filepath.Join(d, "f.log")
+	w, err := newFileWriter(Configuration{
+		FileMaxSize: 100,
+		Filename:    p,
+	})
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating file writer failed: %w", err))
+	}
+
+	// Write
+	const goroutines, writes = 10, 100
+	wg := &sync.WaitGroup{}
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < writes; j++ {
+				w.Write([]byte(fmt.Sprintf("line-%d-%d\n", i, j))) //nolint: errcheck
+			}
+		}(i)
+	}
+	wg.Wait()
+	w.Close()
+
+	// Read all files
+	ps, err := filepath.Glob(p + "*")
+	if err != nil {
+		t.Fatal(fmt.Errorf("globbing failed: %w", err))
+	}
+	lines := make(map[string]bool)
+	for _, p := range ps {
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			t.Fatal(fmt.Errorf("reading %s failed: %w", p, err))
+		}
+		if len(b) > 100 {
+			t.Errorf("expected %s size <= 100, got %d", p, len(b))
+		}
+		for _, l := range strings.Split(strings.TrimSuffix(string(b), "\n"), "\n") {
+			if !strings.HasPrefix(l, "line-") {
+				t.Errorf("invalid line %s in %s", l, p)
+			}
+			lines[l] = true
+		}
+	}
+	if e, g := goroutines*writes, len(lines); e != g {
+		t.Errorf("expected %d, got %d", e, g)
+	}
+}
+
+func TestRenderFilename(t *testing.T) {
+	n := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, v := range []struct {
+		e, i string
+	}{
+		{e: "app.log", i: "app.log"},
+		{e: "app-2020-01-02.log", i: "app-{2006-01-02}.log"},
+		{e: "2020/app-03.log", i: "{2006}/app-{15}.log"},
+		{e: "app-{2006.log", i: "app-{2006.log"},
+	} {
+		if g := renderFilename(v.i, n); v.e != g {
+			t.Errorf("expected %s, got %s", v.e, g)
+		}
+	}
+}
+
+func TestFileWriterTimeTemplate(t *testing.T) {
+	// Create temp dir
+	d, err := ioutil.TempDir("", "astilog_")
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating temp dir failed: %w", err))
+	}
+
+	// Make sure to delete directory
+	defer os.RemoveAll(d)
+
+	// Mock now
+	oldNow := now
+	defer func() { now = oldNow }()
+	n := time.Date(2020, 1, 2, 23, 0, 0, 0, time.UTC)
+	now = func() time.Time { return n }
+
+	// Create writer
+	s := filepath.Join(d, "current.log")
+	w, err := newFileWriter(Configuration{
+		FileSymlink: s,
+		Filename:    filepath.Join(d, "app-{2006-01-02}.log"),
+	})
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating file writer failed: %w", err))
+	}
+	defer w.Close()
+
+	// Assert helper
+	assertFile := func(p, e string) {
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			t.Fatal(fmt.Errorf("reading %s failed: %w", p, err))
+		}
+		if !bytes.Equal([]byte(e), b) {
+			t.Errorf("expected %s, got %s", e, b)
+		}
+	}
+
+	// Same period
+	w.Write([]byte("1\n")) //nolint: errcheck
+	n = n.Add(59 * time.Minute)
+	w.Write([]byte("2\n")) //nolint: errcheck
+	assertFile(filepath.Join(d, "app-2020-01-02.log"), "1\n2\n")
+	assertFile(s, "1\n2\n")
+
+	// Period boundary has passed
+	n = n.Add(time.Minute)
+	w.Write([]byte("3\n")) //nolint: errcheck
+	assertFile(filepath.Join(d, "app-2020-01-02.log"), "1\n2\n")
+	assertFile(filepath.Join(d, "app-2020-01-03.log"), "3\n")
+	assertFile(s, "3\n")
+}
+
+func TestFileWriterHousekeeping(t *testing.T) {
+	// Create temp dir
+	d, err := ioutil.TempDir("", "astilog_")
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating temp dir failed: %w", err))
+	}
+
+	// Make sure to delete directory
+	defer os.RemoveAll(d)
+
+	// Mock now
+	oldNow := now
+	defer func() { now = oldNow }()
+	n := time.Date(2020, 1, 5, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return n }
+
+	// Create old files
+	for _, v := range []struct {
+		age  time.Duration
+		path string
+	}{
+		{age: 72 * time.Hour, path: "app-2020-01-01.log"},
+		{age: 48 * time.Hour, path: "app-2020-01-02.log.1.gz"},
+		{age: 24 * time.Hour, path: "app-2020-01-03.log"},
+		{age: time.Hour, path: "app-2020-01-04.log"},
+		{path: "other.log"},
+	} {
+		p := filepath.Join(d, v.path)
+		if err = ioutil.WriteFile(p, []byte(v.path), 0755); err != nil {
+			t.Fatal(fmt.Errorf("writing %s failed: %w", p, err))
+		}
+		if err = os.Chtimes(p, n.Add(-v.age), n.Add(-v.age)); err != nil {
+			t.Fatal(fmt.Errorf("updating %s times failed: %w", p, err))
+		}
+	}
+
+	// Create writer
+	w, err := newFileWriter(Configuration{
+		FileCompress:   true,
+		FileMaxAge:     36 * time.Hour,
+		FileMaxBackups: 3,
+		Filename:       filepath.Join(d, "app-{2006-01-02}.log"),
+	})
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating file writer failed: %w", err))
+	}
+	w.Write([]byte("test\n")) //nolint: errcheck
+	w.Close()
+
+	// Assert
+	ps, err := filepath.Glob(filepath.Join(d, "*"))
+	if err != nil {
+		t.Fatal(fmt.Errorf("globbing failed: %w", err))
+	}
+	var gs []string
+	for _, p := range ps {
+		gs = append(gs, filepath.Base(p))
+	}
+	if e := []string{"app-2020-01-03.log.gz", "app-2020-01-04.log.gz", "app-2020-01-05.log", "other.log"}; !reflect.DeepEqual(e, gs) {
+		t.Errorf("expected %+v, got %+v", e, gs)
+	}
+
+	// Compressed content
+	f, err := os.Open(filepath.Join(d, "app-2020-01-04.log.gz"))
+	if err != nil {
+		t.Fatal(fmt.Errorf("opening failed: %w", err))
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating gzip reader failed: %w", err))
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(fmt.Errorf("reading failed: %w", err))
+	}
+	if e := "app-2020-01-04.log"; e != string(b) {
+		t.Errorf("expected %s, got %s", e, b)
+	}
+}
+
+func TestFileWriterReopen(t *testing.T) {
+	// Create temp dir
+	d, err := ioutil.TempDir("", "astilog_")
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating temp dir failed: %w", err))
+	}
+
+	// Make sure to delete directory
+	defer os.RemoveAll(d)
+
+	// Create writer
+	p := filepath.Join(d, "f.log")
+	w, err := newFileWriter(Configuration{Filename: p})
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating file writer failed: %w", err))
+	}
+	defer w.Close()
+
+	// Assert helper
+	assertFile := func(p, e string) {
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			t.Fatal(fmt.Errorf("reading %s failed: %w", p, err))
+		}
+		if !bytes.Equal([]byte(e), b) {
+			t.Errorf("expected %s, got %s", e, b)
+		}
+	}
+
+	// Move file
+	w.Write([]byte("1\n")) //nolint: errcheck
+	if err = os.Rename(p, p+".old"); err != nil {
+		t.Fatal(fmt.Errorf("renaming failed: %w", err))
+	}
+	w.Write([]byte("2\n")) //nolint: errcheck
+
+	// Reopen
+	if err = w.Reopen(); err != nil {
+		t.Fatal(fmt.Errorf("reopening failed: %w", err))
+	}
+	w.Write([]byte("3\n")) //nolint: errcheck
+	assertFile(p+".old", "1\n2\n")
+	assertFile(p, "3\n")
+}
+
+func TestFileWriterOpenFailure(t *testing.T) {
+	// Create temp dir
+	d, err := ioutil.TempDir("", "astilog_")
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating temp dir failed: %w", err))
+	}
+
+	// Make sure to delete directory
+	defer os.RemoveAll(d)
+
+	// Create writer
+	p := filepath.Join(d, "f.log")
+	w, err := newFileWriter(Configuration{Filename: p})
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating file writer failed: %w", err))
+	}
+	defer w.Close()
+
+	// Simulate a rotation where the file couldn't be opened after being renamed
+	if _, err = w.Write([]byte("1\n")); err != nil {
+		t.Fatal(fmt.Errorf("writing failed: %w", err))
+	}
+	w.f.Close()
+	w.f = nil
+	if err = os.Rename(p, p+".1"); err != nil {
+		t.Fatal(fmt.Errorf("renaming failed: %w", err))
+	}
+	if err = os.Mkdir(p, 0755); err != nil {
+		t.Fatal(fmt.Errorf("creating directory failed: %w", err))
+	}
+
+	// Opening fails
+	if _, err = w.Write([]byte("2\n")); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if w.f != nil {
+		t.Fatal("expected nil file")
+	}
+
+	// Opening succeeds
+	if err = os.Remove(p); err != nil {
+		t.Fatal(fmt.Errorf("removing failed: %w", err))
+	}
+	if _, err = w.Write([]byte("3\n")); err != nil {
+		t.Fatal(fmt.Errorf("writing failed: %w", err))
+	}
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		t.Fatal(fmt.Errorf("reading %s failed: %w", p, err))
+	}
+	if e, g := "3\n", string(b); e != g {
+		t.Errorf("expected %s, got %s", e, g)
+	}
+
+	// Writer is closed
+	w.Close()
+	if _, err = w.Write([]byte("4\n")); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestFileWriterSwitchFailure(t *testing.T) {
+	// Create temp dir
+	d, err := ioutil.TempDir("", "astilog_")
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating temp dir failed: %w", err))
+	}
+
+	// Make sure to delete directory
+	defer os.RemoveAll(d)
+
+	// Mock now
+	oldNow := now
+	defer func() { now = oldNow }()
+	n := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return n }
+
+	// Create writer
+	w, err := newFileWriter(Configuration{Filename: filepath.Join(d, "app-{15}.log")})
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating file writer failed: %w", err))
+	}
+	defer w.Close()
+	if _, err = w.Write([]byte("1\n")); err != nil {
+		t.Fatal(fmt.Errorf("writing failed: %w", err))
+	}
+
+	// Make sure the next period's file can't be opened
+	p := filepath.Join(d, "app-01.log")
+	if err = os.Mkdir(p, 0755); err != nil {
+		t.Fatal(fmt.Errorf("creating directory failed: %w", err))
+	}
+	n = n.Add(time.Hour)
+	if _, err = w.Write([]byte("2\n")); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	// Switching is retried
+	if err = os.Remove(p); err != nil {
+		t.Fatal(fmt.Errorf("removing failed: %w", err))
+	}
+	if _, err = w.Write([]byte("3\n")); err != nil {
+		t.Fatal(fmt.Errorf("writing failed: %w", err))
+	}
+	for p, e := range map[string]string{
+		filepath.Join(d, "app-00.log"): "1\n",
+		filepath.Join(d, "app-01.log"): "3\n",
+	} {
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			t.Fatal(fmt.Errorf("reading %s failed: %w", p, err))
+		}
+		if g := string(b); e != g {
+			t.Errorf("expected %s, got %s", e, g)
+		}
+	}
+}
+
+func TestFileWriterSwitchCloseFailure(t *testing.T) {
+	// Create temp dir
+	d, err := ioutil.TempDir("", "astilog_")
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating temp dir failed: %w", err))
+	}
+
+	// Make sure to delete directory
+	defer os.RemoveAll(d)
+
+	// Mock now
+	oldNow := now
+	defer func() { now = oldNow }()
+	n := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return n }
+
+	// Create writer
+	var errs []error
+	w, err := newFileWriter(Configuration{
+		ErrorHandler: func(err error) { errs = append(errs, err) },
+		Filename:     filepath.Join(d, "app-{15}.log"),
+	})
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating file writer failed: %w", err))
+	}
+	defer w.Close()
+
+	// Make sure closing the current file fails
+	w.f.Close()
+
+	// Switch
+	n = n.Add(time.Hour)
+	if g, err := w.Write([]byte("1\n")); err != nil {
+		t.Fatal(fmt.Errorf("writing failed: %w", err))
+	} else if e := 2; e != g {
+		t.Errorf("expected %d, got %d", e, g)
+	}
+	if e, g := 1, len(errs); e != g {
+		t.Fatalf("expected %d, got %d", e, g)
+	}
+	if e, g := "astilog: closing "+filepath.Join(d, "app-00.log")+" failed", errs[0].Error(); !strings.HasPrefix(g, e) {
+		t.Errorf("expected %s, got %s", e, g)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(d, "app-01.log"))
+	if err != nil {
+		t.Fatal(fmt.Errorf("reading failed: %w", err))
+	}
+	if e, g := "1\n", string(b); e != g {
+		t.Errorf("expected %s, got %s", e, g)
+	}
+}
+
+func TestFileWriterHousekeepingSiblings(t *testing.T) {
+	// Create temp dir
+	d, err := ioutil.TempDir("", "astilog_")
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating temp dir failed: %w", err))
+	}
+
+	// Make sure to delete directory
+	defer os.RemoveAll(d)
+
+	// Create files
+	n := time.Now()
+	for i, p := range []string{
+		"app.log.1",
+		"app.log.2.gz",
+		"app.log.3",
+		"app.log.bak",
+		"app.log.lock",
+		"app.logger",
+		"app-2020-01-01.log",
+	} {
+		p = filepath.Join(d, p)
+		if err = ioutil.WriteFile(p, []byte(p), 0755); err != nil {
+			t.Fatal(fmt.Errorf("writing %s failed: %w", p, err))
+		}
+		mt := n.Add(-time.Duration(i) * time.Hour)
+		if err = os.Chtimes(p, mt, mt); err != nil {
+			t.Fatal(fmt.Errorf("updating %s times failed: %w", p, err))
+		}
+	}
+
+	// Create writer
+	w, err := newFileWriter(Configuration{
+		FileCompress:   true,
+		FileMaxBackups: 1,
+		Filename:       filepath.Join(d, "app.log"),
+	})
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating file writer failed: %w", err))
+	}
+	w.Close()
+
+	// Assert
+	ps, err := filepath.Glob(filepath.Join(d, "*"))
+	if err != nil {
+		t.Fatal(fmt.Errorf("globbing failed: %w", err))
+	}
+	var gs []string
+	for _, p := range ps {
+		gs = append(gs, filepath.Base(p))
+	}
+	if e := []string{"app-2020-01-01.log", "app.log", "app.log.1.gz", "app.log.bak", "app.log.lock", "app.logger"}; !reflect.DeepEqual(e, gs) {
+		t.Errorf("expected %+v, got %+v", e, gs)
+	}
+
+	// Matcher
+	m := newFilenameMatcher(filepath.Join("dir", "{2006}", "app-{2006-01-02}.log"))
+	for p, e := range map[string]bool{
+		filepath.Join("dir", "2020", "app-2020-01-02.log"):        true,
+		filepath.Join("dir", "2020", "app-2020-01-02.log.1"):      true,
+		filepath.Join("dir", "2020", "app-2020-01-02.log.1.gz"):   true,
+		filepath.Join("dir", "2020", "app-2020-01-02.log.gz"):     true,
+		filepath.Join("dir", "2020", "app-2020-01-02.log.0"):      false,
+		filepath.Join("dir", "2020", "app-2020-01-02.log.bak"):    false,
+		filepath.Join("dir", "2020", "app-2020-01-02.log.bak.gz"): false,
+		filepath.Join("dir", "2020", "app-latest.log"):            false,
+		filepath.Join("dir", "other", "app-2020-01-02.log"):       false,
+	} {
+		if g := m.matchWithBackup(p); e != g {
+			t.Errorf("expected %v, got %v for %s", e, g, p)
+		}
+	}
+}
+
+func TestFileWriterHousekeepingRelativePath(t *testing.T) {
+	// Create temp dir
+	d, err := ioutil.TempDir("", "astilog_")
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating temp dir failed: %w", err))
+	}
+
+	// Make sure to delete directory
+	defer os.RemoveAll(d)
+
+	// Change working directory
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(fmt.Errorf("getting working directory failed: %w", err))
+	}
+	if err = os.Chdir(d); err != nil {
+		t.Fatal(fmt.Errorf("changing working directory failed: %w", err))
+	}
+	defer os.Chdir(wd) //nolint: errcheck
+
+	// Mock now
+	oldNow := now
+	defer func() { now = oldNow }()
+	n := time.Date(2020, 1, 5, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return n }
+
+	// Create old file
+	if err = ioutil.WriteFile("app-2020-01-04.log", []byte("old"), 0755); err != nil {
+		t.Fatal(fmt.Errorf("writing failed: %w", err))
+	}
+
+	// Create writer
+	w, err := newFileWriter(Configuration{
+		FileCompress: true,
+		FileSymlink:  "./app.log",
+		Filename:     "./app-{2006-01-02}.log",
+	})
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating file writer failed: %w", err))
+	}
+	if _, err = w.Write([]byte("1\n")); err != nil {
+		t.Fatal(fmt.Errorf("writing failed: %w", err))
+	}
+	w.housekeepInBackground()
+	if _, err = w.Write([]byte("2\n")); err != nil {
+		t.Fatal(fmt.Errorf("writing failed: %w", err))
+	}
+	w.Close()
+
+	// Assert
+	ps, err := filepath.Glob("*")
+	if err != nil {
+		t.Fatal(fmt.Errorf("globbing failed: %w", err))
+	}
+	if e := []string{"app-2020-01-04.log.gz", "app-2020-01-05.log", "app.log"}; !reflect.DeepEqual(e, ps) {
+		t.Errorf("expected %+v, got %+v", e, ps)
+	}
+	b, err := ioutil.ReadFile("app-2020-01-05.log")
+	if err != nil {
+		t.Fatal(fmt.Errorf("reading failed: %w", err))
+	}
+	if e, g := "1\n2\n", string(b); e != g {
+		t.Errorf("expected %s, got %s", e, g)
+	}
+}
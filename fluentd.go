@@ -0,0 +1,220 @@
+package astilog
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/asticode/go-astikit"
+)
+
+const (
+	fluentdDefaultTag = "astilog"
+	fluentdTimeout    = 5 * time.Second
+)
+
+// fluentdWriter sends entries to fluentd or fluent-bit using the Forward mode of the
+// forward protocol. When acks are required, each message has a chunk id and the writer
+// waits for the server to acknowledge it.
+type fluentdWriter struct {
+	address string
+	c       Configuration
+	conn    net.Conn
+	m       *sync.Mutex // Locks conn and r
+	msgKey  string
+	network string
+	r       *bufio.Reader
+	tag     string
+}
+
+func newFluentdWriter(c Configuration) (w *fluentdWriter, err error) {
+	// Create
+	w = &fluentdWriter{
+		c:      c,
+		m:      &sync.Mutex{},
+		msgKey: "msg",
+		tag:    fluentdDefaultTag,
+	}
+
+	// Get message key
+	if c.MessageKey != "" {
+		w.msgKey = c.MessageKey
+	}
+
+	// Get tag
+	if c.AppName != "" {
+		w.tag = c.AppName
+	}
+
+	// Parse address
+	if w.network, w.address, err = parseNetworkAddress(c.FluentdAddress, "tcp"); err != nil {
+		return
+	}
+	switch w.network {
+	case "tcp", "unix":
+	default:
+		err = fmt.Errorf("unknown fluentd network %s", w.network)
+		return
+	}
+
+	// Dial
+	if err = w.dial(); err != nil {
+		return
+	}
+	return
+}
+
+func (w *fluentdWriter) dial() (err error) {
+	// Dial
+	if w.conn, err = net.DialTimeout(w.network, w.address, fluentdTimeout); err != nil {
+		w.conn = nil
+		err = fmt.Errorf("dialing %s failed: %w", w.c.FluentdAddress, err)
+		return
+	}
+
+	// Create reader
+	w.r = bufio.NewReader(w.conn)
+	return
+}
+
+// Close implements the io.Closer interface
+func (w *fluentdWriter) Close() error {
+	w.m.Lock()
+	defer w.m.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// Write implements the io.Writer interface
+func (w *fluentdWriter) Write(p []byte) (int, error) {
+	if err := w.writeEntry(entry{
+		l:   astikit.LoggerLevelInfo,
+		msg: string(bytes.TrimRight(p, "\n")),
+		t:   now(),
+	}, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// buildsPayload implements the payloadBuilder interface
+func (w *fluentdWriter) buildsPayload() bool {
+	return true
+}
+
+func (w *fluentdWriter) writeEntry(e entry, _ []byte) (err error) {
+	// Create chunk id
+	var chunk string
+	if w.c.FluentdRequireAck {
+		b := make([]byte, 16)
+		if _, err = rand.Read(b); err != nil {
+			return fmt.Errorf("creating chunk id failed: %w", err)
+		}
+		chunk = base64.StdEncoding.EncodeToString(b)
+	}
+
+	// Build message
+	m := w.message(e, chunk)
+
+	// Lock
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	// Send and reconnect once if it failed
+	if err = w.send(m, chunk); err != nil {
+		if errDial := w.dial(); errDial != nil {
+			return fmt.Errorf("reconnecting failed: %w", errDial)
+		}
+		if err = w.send(m, chunk); err != nil {
+			return
+		}
+	}
+
+	// Count
+	e.cs.written(len(m), nil)
+	return
+}
+
+// message builds a Forward mode message: [tag, [[time, record]], option]
+func (w *fluentdWriter) message(e entry, chunk string) []byte {
+	// Create record
+	r := make(map[string]interface{}, len(e.fs)+2)
+	for k, v := range e.fs {
+		r[k] = v
+	}
+	r[w.msgKey] = e.msg
+	r["level"] = e.l.String()
+
+	// Create option
+	o := map[string]interface{}{"size": 1}
+	if chunk != "" {
+		o["chunk"] = chunk
+	}
+
+	// Append
+	b := msgpackAppendArrayHeader(nil, 3)
+	b = msgpackAppendString(b, w.tag)
+	b = msgpackAppendArrayHeader(b, 1)
+	b = msgpackAppendArrayHeader(b, 2)
+	b = msgpackAppendEventTime(b, e.t)
+	b = msgpackAppend(b, r)
+	return msgpackAppend(b, o)
+}
+
+func (w *fluentdWriter) send(m []byte, chunk string) (err error) {
+	// No connection
+	if w.conn == nil {
+		return errors.New("not connected")
+	}
+
+	// Make sure to close the connection on error
+	defer func() {
+		if err != nil {
+			w.conn.Close()
+			w.conn = nil
+		}
+	}()
+
+	// Write
+	if err = w.conn.SetWriteDeadline(time.Now().Add(fluentdTimeout)); err != nil {
+		err = fmt.Errorf("setting write deadline failed: %w", err)
+		return
+	}
+	if _, err = w.conn.Write(m); err != nil {
+		err = fmt.Errorf("writing failed: %w", err)
+		return
+	}
+
+	// No ack
+	if chunk == "" {
+		return
+	}
+
+	// Read ack
+	if err = w.conn.SetReadDeadline(time.Now().Add(fluentdTimeout)); err != nil {
+		err = fmt.Errorf("setting read deadline failed: %w", err)
+		return
+	}
+	var v interface{}
+	if v, err = msgpackDecode(w.r); err != nil {
+		err = fmt.Errorf("reading ack failed: %w", err)
+		return
+	}
+
+	// Check ack
+	if a, ok := v.(map[string]interface{}); !ok || a["ack"] != chunk {
+		err = fmt.Errorf("invalid ack %v", v)
+		return
+	}
+	return
+}
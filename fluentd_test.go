@@ -0,0 +1,186 @@
+package astilog
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astikit"
+)
+
+// testFluentdServer reads count forward messages and acks them if they have a chunk id
+func testFluentdServer(ln net.Listener, count int) <-chan []interface{} {
+	ch := make(chan []interface{}, 1)
+	go func() {
+		var ms []interface{}
+		defer func() { ch <- ms }()
+
+		// Accept
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// Loop
+		r := bufio.NewReader(conn)
+		for i := 0; i < count; i++ {
+			// Decode
+			m, err := msgpackDecode(r)
+			if err != nil {
+				return
+			}
+			ms = append(ms, m)
+
+			// Ack
+			if a, ok := m.([]interface{}); ok && len(a) == 3 {
+				if o, ok := a[2].(map[string]interface{}); ok && o["chunk"] != nil {
+					if _, err = conn.Write(msgpackAppend(nil, map[string]interface{}{"ack": o["chunk"]})); err != nil {
+						return
+					}
+				}
+			}
+		}
+	}()
+	return ch
+}
+
+func testFluentdWriter(t *testing.T, ln net.Listener, address string, requireAck bool) {
+	// Start server
+	ch := testFluentdServer(ln, 1)
+
+	// Create writer
+	w, err := newFluentdWriter(Configuration{
+		AppName:           "app",
+		FluentdAddress:    address,
+		FluentdRequireAck: requireAck,
+	})
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating writer failed: %w", err))
+	}
+	defer w.Close()
+
+	// Write
+	n := time.Unix(5, 6)
+	if err = w.writeEntry(entry{
+		fs:  map[string]interface{}{"k": "v"},
+		l:   astikit.LoggerLevelWarn,
+		msg: "msg",
+		t:   n,
+	}, nil); err != nil {
+		t.Fatal(fmt.Errorf("writing failed: %w", err))
+	}
+
+	// Assert
+	ms := <-ch
+	if e, g := 1, len(ms); e != g {
+		t.Fatalf("expected %d, got %d", e, g)
+	}
+	m, ok := ms[0].([]interface{})
+	if !ok || len(m) != 3 {
+		t.Fatalf("invalid message %+v", ms[0])
+	}
+	if e, g := "app", m[0]; e != g {
+		t.Errorf("expected %s, got %s", e, g)
+	}
+	es, ok := m[1].([]interface{})
+	if !ok || len(es) != 1 {
+		t.Fatalf("invalid entries %+v", m[1])
+	}
+	e, ok := es[0].([]interface{})
+	if !ok || len(e) != 2 {
+		t.Fatalf("invalid entry %+v", es[0])
+	}
+	tv, err := msgpackEventTime(e[0])
+	if err != nil {
+		t.Error(fmt.Errorf("expected no error, got %w", err))
+	}
+	if !tv.Equal(n) {
+		t.Errorf("expected %s, got %s", n, tv)
+	}
+	if e, g := map[string]interface{}{"k": "v", "level": "warn", "msg": "msg"}, e[1]; !reflect.DeepEqual(e, g) {
+		t.Errorf("expected %+v, got %+v", e, g)
+	}
+	o, ok := m[2].(map[string]interface{})
+	if !ok {
+		t.Fatalf("invalid option %+v", m[2])
+	}
+	if _, g := o["chunk"]; requireAck != g {
+		t.Errorf("expected %v, got %v", requireAck, g)
+	}
+}
+
+func TestFluentdWriterTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(fmt.Errorf("listening failed: %w", err))
+	}
+	defer ln.Close()
+	testFluentdWriter(t, ln, "tcp://"+ln.Addr().String(), true)
+}
+
+func TestFluentdWriterUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "astilog_")
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating temp dir failed: %w", err))
+	}
+	defer os.RemoveAll(dir)
+	ln, err := net.Listen("unix", filepath.Join(dir, "fluent.sock"))
+	if err != nil {
+		t.Skip(fmt.Errorf("listening failed: %w", err))
+	}
+	defer ln.Close()
+	testFluentdWriter(t, ln, "unix://"+filepath.Join(dir, "fluent.sock"), false)
+}
+
+func TestFluentdWriterInvalidAck(t *testing.T) {
+	// Listen
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(fmt.Errorf("listening failed: %w", err))
+	}
+	defer ln.Close()
+
+	// Reply with invalid acks
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					if _, err := msgpackDecode(r); err != nil {
+						return
+					}
+					if _, err := conn.Write(msgpackAppend(nil, map[string]interface{}{"ack": "invalid"})); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+
+	// Create writer
+	w, err := newFluentdWriter(Configuration{
+		FluentdAddress:    ln.Addr().String(),
+		FluentdRequireAck: true,
+	})
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating writer failed: %w", err))
+	}
+	defer w.Close()
+
+	// Write
+	if _, err = w.Write([]byte("msg\n")); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
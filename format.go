@@ -3,70 +3,153 @@ package astilog
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"sort"
 	"strconv"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/asticode/go-astikit"
 )
 
-type formatter interface {
-	format(msg string, l astikit.LoggerLevel, fs map[string]interface{}) []byte
+// Entry represents a log entry as it's provided to formatters
+type Entry struct {
+	Fields  map[string]interface{}
+	Level   astikit.LoggerLevel
+	Message string
+	// Source is the "file:line" that called the log function. It's only set when the
+	// Source option is true, in which case it's also added to fields with the "source"
+	// key.
+	Source string
+	Time   time.Time
+}
+
+// Formatter formats entries. Fields may be shared between sinks and must not be
+// modified.
+type Formatter interface {
+	Format(e Entry) ([]byte, error)
+}
+
+// FormatterFactory creates a formatter based on the sink configuration. createdAt is the
+// time the logger has been created at.
+type FormatterFactory func(c Configuration, createdAt time.Time) (Formatter, error)
+
+var (
+	formatterFactories = map[string]FormatterFactory{
+		FormatECS: func(c Configuration, createdAt time.Time) (Formatter, error) {
+			return newECSFormatter(c), nil
+		},
+		FormatGELF: func(c Configuration, createdAt time.Time) (Formatter, error) {
+			return newGELFFormatter(), nil
+		},
+		FormatJSON: func(c Configuration, createdAt time.Time) (Formatter, error) {
+			return newJSONFormatter(c, createdAt), nil
+		},
+		FormatLogfmt: func(c Configuration, createdAt time.Time) (Formatter, error) {
+			return newLogfmtFormatter(c), nil
+		},
+		FormatMinimalist: func(c Configuration, createdAt time.Time) (Formatter, error) {
+			return newMinimalistFormatter(), nil
+		},
+		FormatTemplate: func(c Configuration, createdAt time.Time) (Formatter, error) {
+			return newTemplateFormatter(c, createdAt)
+		},
+		FormatText: func(c Configuration, createdAt time.Time) (Formatter, error) {
+			return newTextFormatter(c, createdAt), nil
+		},
+	}
+	formatterFactoriesMutex = &sync.RWMutex{} // Locks formatterFactories
+)
+
+// RegisterFormat registers a format that can then be used in the Format option. If a
+// format with the same name already exists, builtin formats included, it's replaced.
+// Formats must be registered before creating the loggers using them.
+func RegisterFormat(name string, f FormatterFactory) {
+	formatterFactoriesMutex.Lock()
+	defer formatterFactoriesMutex.Unlock()
+	formatterFactories[name] = f
+}
+
+func newFormatter(format string, c Configuration, createdAt time.Time) (Formatter, error) {
+	// Get factory
+	formatterFactoriesMutex.RLock()
+	f, ok := formatterFactories[format]
+	formatterFactoriesMutex.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown format %s", format)
+	}
+
+	// Create
+	return f(c, createdAt)
 }
 
 type textFormatter struct {
 	c         Configuration
+	color     bool
 	createdAt time.Time
 }
 
 func newTextFormatter(c Configuration, createdAt time.Time) *textFormatter {
 	return &textFormatter{
 		c:         c,
+		color:     colorEnabled(c),
 		createdAt: createdAt,
 	}
 }
 
-func (f *textFormatter) format(msg string, l astikit.LoggerLevel, fs map[string]interface{}) (b []byte) {
+// Format implements the Formatter interface
+func (f *textFormatter) Format(e Entry) (b []byte, err error) {
 	// Add level
-	switch l {
+	var l string
+	switch e.Level {
 	case astikit.LoggerLevelDebug:
-		b = append(b, []byte("DEBUG")...)
+		l = "DEBUG"
 	case astikit.LoggerLevelWarn:
-		b = append(b, []byte(" WARN")...)
+		l = " WARN"
 	case astikit.LoggerLevelError:
-		b = append(b, []byte("ERROR")...)
+		l = "ERROR"
 	case astikit.LoggerLevelFatal:
-		b = append(b, []byte("FATAL")...)
+		l = "FATAL"
 	default:
-		b = append(b, []byte(" INFO")...)
+		l = " INFO"
 	}
+	b = colorAppend(b, l, colorLevel(e.Level), f.color)
 
 	// Add timestamp
-	b = append(b, []byte("[")...)
+	var ts string
 	if f.c.TimestampFormat == "" {
-		b = append(b, astikit.BytesPad([]byte(strconv.Itoa(int(now().Sub(f.createdAt).Seconds()))), '0', 4)...)
+		ts = string(astikit.BytesPad([]byte(strconv.Itoa(int(e.Time.Sub(f.createdAt).Seconds()))), '0', 4))
 	} else {
-		b = append(b, []byte(now().Format(f.c.TimestampFormat))...)
+		ts = e.Time.Format(f.c.TimestampFormat)
 	}
+	b = append(b, []byte("[")...)
+	b = colorAppend(b, ts, colorDim, f.color)
 	b = append(b, []byte("]")...)
 
 	// Add msg
-	b = append(b, []byte(msg)...)
+	b = append(b, []byte(e.Message)...)
 
 	// Add fields
-	if len(fs) > 0 {
+	if len(e.Fields) > 0 {
 		// Add spaces
 		b = append(b, []byte("  ")...)
 
 		// Sort fields
-		var vs []string
-		for k, v := range fs {
-			vs = append(vs, k+"="+fmt.Sprintf("%v", v))
+		type field struct{ k, v string }
+		var fs []field
+		for k, v := range e.Fields {
+			fs = append(fs, field{k: k, v: fmt.Sprintf("%v", v)})
+		}
+		sort.Slice(fs, func(i, j int) bool { return fs[i].k+"="+fs[i].v < fs[j].k+"="+fs[j].v })
+
+		// Loop through fields
+		for idx, fd := range fs {
+			if idx > 0 {
+				b = append(b, ' ')
+			}
+			b = colorAppend(b, fd.k, colorKey, f.color)
+			b = append(b, '=')
+			b = append(b, fd.v...)
 		}
-		sort.Strings(vs)
-		b = append(b, []byte(strings.Join(vs, " "))...)
 	}
 
 	// Add newline
@@ -92,30 +175,36 @@ func newJSONFormatter(c Configuration, createdAt time.Time) (f *jsonFormatter) {
 	return
 }
 
-func (f *jsonFormatter) format(msg string, l astikit.LoggerLevel, fs map[string]interface{}) []byte {
+// Format implements the Formatter interface
+func (f *jsonFormatter) Format(e Entry) ([]byte, error) {
+	// Fields may be shared between sinks and can't be modified
+	fs := make(map[string]interface{}, len(e.Fields)+3)
+	for k, v := range e.Fields {
+		fs[k] = v
+	}
+
 	// Add msg
-	fs[f.msgKey] = msg
+	fs[f.msgKey] = e.Message
 
 	// Add level
-	fs["level"] = l
+	fs["level"] = e.Level
 
 	// Add timestamp
 	if f.c.TimestampFormat == "" {
-		fs["time"] = int(now().Sub(f.createdAt).Seconds())
+		fs["time"] = int(e.Time.Sub(f.createdAt).Seconds())
 	} else {
-		fs["time"] = now().Format(f.c.TimestampFormat)
+		fs["time"] = e.Time.Format(f.c.TimestampFormat)
 	}
 
 	// Marshal
 	b, err := json.Marshal(fs)
 	if err != nil {
-		log.Println(fmt.Errorf("astilog: marshaling failed: %w", err))
-		return nil
+		return nil, fmt.Errorf("marshaling failed: %w", err)
 	}
 
 	// Add newline
 	b = append(b, newLine...)
-	return b
+	return b, nil
 }
 
 type minimalistFormatter struct{}
@@ -124,6 +213,7 @@ func newMinimalistFormatter() *minimalistFormatter {
 	return &minimalistFormatter{}
 }
 
-func (f *minimalistFormatter) format(msg string, l astikit.LoggerLevel, fs map[string]interface{}) []byte {
-	return append([]byte(msg), newLine...)
+// Format implements the Formatter interface
+func (f *minimalistFormatter) Format(e Entry) ([]byte, error) {
+	return append([]byte(e.Message), newLine...), nil
 }
@@ -2,39 +2,54 @@ package astilog
 
 import (
 	"bytes"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/asticode/go-astikit"
 )
 
+func testFormat(t *testing.T, f Formatter, msg string, l astikit.LoggerLevel, fs map[string]interface{}) []byte {
+	b, err := f.Format(Entry{
+		Fields:  fs,
+		Level:   l,
+		Message: msg,
+		Time:    now(),
+	})
+	if err != nil {
+		t.Fatal(fmt.Errorf("formatting failed: %w", err))
+	}
+	return b
+}
+
 func TestTextFormatter(t *testing.T) {
 	oldNow := now
 	defer func() { now = oldNow }()
 	now = func() time.Time { return time.Unix(5, 0).UTC() }
 
 	f := newTextFormatter(Configuration{}, time.Unix(0, 0).UTC())
-	if e, g := []byte("DEBUG[0005]msg  k1=v1 k2=v2\n"), f.format("msg", astikit.LoggerLevelDebug, map[string]interface{}{
+	if e, g := []byte("DEBUG[0005]msg  k1=v1 k2=v2\n"), testFormat(t, f, "msg", astikit.LoggerLevelDebug, map[string]interface{}{
 		"k1": "v1",
 		"k2": "v2",
 	}); !bytes.Equal(e, g) {
 		t.Errorf("expected %s, got %s", e, g)
 	}
-	if e, g := []byte(" INFO[0005]msg\n"), f.format("msg", astikit.LoggerLevelInfo, map[string]interface{}{}); !bytes.Equal(e, g) {
+	if e, g := []byte(" INFO[0005]msg\n"), testFormat(t, f, "msg", astikit.LoggerLevelInfo, map[string]interface{}{}); !bytes.Equal(e, g) {
 		t.Errorf("expected %s, got %s", e, g)
 	}
-	if e, g := []byte(" WARN[0005]msg\n"), f.format("msg", astikit.LoggerLevelWarn, map[string]interface{}{}); !bytes.Equal(e, g) {
+	if e, g := []byte(" WARN[0005]msg\n"), testFormat(t, f, "msg", astikit.LoggerLevelWarn, map[string]interface{}{}); !bytes.Equal(e, g) {
 		t.Errorf("expected %s, got %s", e, g)
 	}
-	if e, g := []byte("ERROR[0005]msg\n"), f.format("msg", astikit.LoggerLevelError, map[string]interface{}{}); !bytes.Equal(e, g) {
+	if e, g := []byte("ERROR[0005]msg\n"), testFormat(t, f, "msg", astikit.LoggerLevelError, map[string]interface{}{}); !bytes.Equal(e, g) {
 		t.Errorf("expected %s, got %s", e, g)
 	}
-	if e, g := []byte("FATAL[0005]msg\n"), f.format("msg", astikit.LoggerLevelFatal, map[string]interface{}{}); !bytes.Equal(e, g) {
+	if e, g := []byte("FATAL[0005]msg\n"), testFormat(t, f, "msg", astikit.LoggerLevelFatal, map[string]interface{}{}); !bytes.Equal(e, g) {
 		t.Errorf("expected %s, got %s", e, g)
 	}
 
 	f = newTextFormatter(Configuration{TimestampFormat: time.RFC3339}, time.Unix(0, 0))
-	if e, g := []byte(" INFO[1970-01-01T00:00:05Z]msg\n"), f.format("msg", astikit.LoggerLevelInfo, map[string]interface{}{}); !bytes.Equal(e, g) {
+	if e, g := []byte(" INFO[1970-01-01T00:00:05Z]msg\n"), testFormat(t, f, "msg", astikit.LoggerLevelInfo, map[string]interface{}{}); !bytes.Equal(e, g) {
 		t.Errorf("expected %s, got %s", e, g)
 	}
 }
@@ -45,22 +60,22 @@ func TestJSONFormatter(t *testing.T) {
 	now = func() time.Time { return time.Unix(5, 0).UTC() }
 
 	f := newJSONFormatter(Configuration{}, time.Unix(0, 0).UTC())
-	if e, g := []byte(`{"k1":"v1","k2":"v2","level":"debug","msg":"msg","time":5}`+"\n"), f.format("msg", astikit.LoggerLevelDebug, map[string]interface{}{
+	if e, g := []byte(`{"k1":"v1","k2":"v2","level":"debug","msg":"msg","time":5}`+"\n"), testFormat(t, f, "msg", astikit.LoggerLevelDebug, map[string]interface{}{
 		"k1": "v1",
 		"k2": "v2",
 	}); !bytes.Equal(e, g) {
 		t.Errorf("expected %s, got %s", e, g)
 	}
-	if e, g := []byte(`{"level":"info","msg":"msg","time":5}`+"\n"), f.format("msg", astikit.LoggerLevelInfo, map[string]interface{}{}); !bytes.Equal(e, g) {
+	if e, g := []byte(`{"level":"info","msg":"msg","time":5}`+"\n"), testFormat(t, f, "msg", astikit.LoggerLevelInfo, map[string]interface{}{}); !bytes.Equal(e, g) {
 		t.Errorf("expected %s, got %s", e, g)
 	}
-	if e, g := []byte(`{"level":"warn","msg":"msg","time":5}`+"\n"), f.format("msg", astikit.LoggerLevelWarn, map[string]interface{}{}); !bytes.Equal(e, g) {
+	if e, g := []byte(`{"level":"warn","msg":"msg","time":5}`+"\n"), testFormat(t, f, "msg", astikit.LoggerLevelWarn, map[string]interface{}{}); !bytes.Equal(e, g) {
 		t.Errorf("expected %s, got %s", e, g)
 	}
-	if e, g := []byte(`{"level":"error","msg":"msg","time":5}`+"\n"), f.format("msg", astikit.LoggerLevelError, map[string]interface{}{}); !bytes.Equal(e, g) {
+	if e, g := []byte(`{"level":"error","msg":"msg","time":5}`+"\n"), testFormat(t, f, "msg", astikit.LoggerLevelError, map[string]interface{}{}); !bytes.Equal(e, g) {
 		t.Errorf("expected %s, got %s", e, g)
 	}
-	if e, g := []byte(`{"level":"fatal","msg":"msg","time":5}`+"\n"), f.format("msg", astikit.LoggerLevelFatal, map[string]interface{}{}); !bytes.Equal(e, g) {
+	if e, g := []byte(`{"level":"fatal","msg":"msg","time":5}`+"\n"), testFormat(t, f, "msg", astikit.LoggerLevelFatal, map[string]interface{}{}); !bytes.Equal(e, g) {
 		t.Errorf("expected %s, got %s", e, g)
 	}
 
@@ -68,17 +83,67 @@ func TestJSONFormatter(t *testing.T) {
 		MessageKey:      "msg_test",
 		TimestampFormat: time.RFC3339,
 	}, time.Unix(0, 0))
-	if e, g := []byte(`{"level":"info","msg_test":"msg","time":"1970-01-01T00:00:05Z"}`+"\n"), f.format("msg", astikit.LoggerLevelInfo, map[string]interface{}{}); !bytes.Equal(e, g) {
+	if e, g := []byte(`{"level":"info","msg_test":"msg","time":"1970-01-01T00:00:05Z"}`+"\n"), testFormat(t, f, "msg", astikit.LoggerLevelInfo, map[string]interface{}{}); !bytes.Equal(e, g) {
 		t.Errorf("expected %s, got %s", e, g)
 	}
 }
 
 func TestMinimalistFormatter(t *testing.T) {
 	f := newMinimalistFormatter()
-	if e, g := []byte("msg\n"), f.format("msg", astikit.LoggerLevelDebug, map[string]interface{}{
+	if e, g := []byte("msg\n"), testFormat(t, f, "msg", astikit.LoggerLevelDebug, map[string]interface{}{
 		"k1": "v1",
 		"k2": "v2",
 	}); !bytes.Equal(e, g) {
 		t.Errorf("expected %s, got %s", e, g)
 	}
 }
+
+type mockedFormatter struct{ prefix string }
+
+func (f mockedFormatter) Format(e Entry) ([]byte, error) {
+	return []byte(f.prefix + e.Level.String() + ":" + e.Message + ":" + e.Source + "\n"), nil
+}
+
+func TestRegisterFormat(t *testing.T) {
+	// Register
+	RegisterFormat("test", func(c Configuration, createdAt time.Time) (Formatter, error) {
+		return mockedFormatter{prefix: c.AppName + ":"}, nil
+	})
+	defer func() {
+		formatterFactoriesMutex.Lock()
+		delete(formatterFactories, "test")
+		formatterFactoriesMutex.Unlock()
+	}()
+
+	// Create logger
+	l := New(Configuration{
+		AppName: "app",
+		Format:  "test",
+		Source:  true,
+	})
+	defer l.Close()
+	buf := &bytes.Buffer{}
+	l.ss[0].w = astikit.NopCloser(buf)
+
+	// Log
+	l.Warn("msg")
+	if e, g := "app:warn:msg:format_test.go:", buf.String(); !strings.HasPrefix(g, e) {
+		t.Errorf("expected prefix %s, got %s", e, g)
+	}
+
+	// Unknown format
+	var errs []error
+	l = New(Configuration{
+		ErrorHandler: func(err error) { errs = append(errs, err) },
+		Format:       "unknown",
+	})
+	defer l.Close()
+	if e, g := 1, len(errs); e != g {
+		t.Errorf("expected %d, got %d", e, g)
+	}
+	switch tp := l.ss[0].f.(type) {
+	case *textFormatter:
+	default:
+		t.Errorf("expected *textFormatter, got %T", tp)
+	}
+}
@@ -0,0 +1,328 @@
+package astilog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// GELF compressions
+const (
+	GELFCompressionGzip = "gzip"
+	GELFCompressionNone = "none"
+	GELFCompressionZlib = "zlib"
+)
+
+// GELF networks
+const (
+	GELFNetworkTCP = "tcp"
+	GELFNetworkUDP = "udp"
+)
+
+const (
+	gelfChunkHeaderSize       = 12
+	gelfDefaultChunkSize      = 1420
+	gelfMaxChunks             = 128
+	gelfTimeout               = 5 * time.Second
+	gelfVersion               = "1.1"
+	gelfAdditionalFieldPrefix = "_"
+)
+
+var gelfChunkMagicBytes = []byte{0x1e, 0x0f}
+
+type gelfFormatter struct {
+	host string
+}
+
+func newGELFFormatter() (f *gelfFormatter) {
+	f = &gelfFormatter{}
+	if h, err := os.Hostname(); err == nil {
+		f.host = h
+	}
+	return
+}
+
+var gelfInvalidFieldChars = regexp.MustCompile(`[^\w\.\-]`)
+
+// Format implements the Formatter interface
+func (f *gelfFormatter) Format(e Entry) ([]byte, error) {
+	// Create message
+	m := map[string]interface{}{
+		"host":          f.host,
+		"level":         syslogSeverity(e.Level),
+		"short_message": e.Message,
+		"timestamp":     float64(e.Time.UnixNano()/int64(time.Millisecond)) / 1e3,
+		"version":       gelfVersion,
+	}
+
+	// Add additional fields
+	for k, v := range e.Fields {
+		// Get name
+		n := gelfAdditionalFieldPrefix + gelfInvalidFieldChars.ReplaceAllString(k, "_")
+		if n == "_id" {
+			n = "__id"
+		}
+
+		// Values can only be strings or numbers
+		switch v.(type) {
+		case string, float32, float64, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+			m[n] = v
+		default:
+			m[n] = fmt.Sprintf("%v", v)
+		}
+	}
+
+	// Marshal
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling failed: %w", err)
+	}
+
+	// Add newline
+	b = append(b, newLine...)
+	return b, nil
+}
+
+// gelfWriter sends GELF messages either compressed and chunked over UDP or null byte
+// delimited over TCP
+type gelfWriter struct {
+	c    Configuration
+	conn net.Conn
+	m    *sync.Mutex // Locks conn
+}
+
+func newGELFWriter(c Configuration) (w *gelfWriter, err error) {
+	// Create
+	w = &gelfWriter{
+		c: c,
+		m: &sync.Mutex{},
+	}
+
+	// Check compression
+	switch c.GELFCompression {
+	case "", GELFCompressionGzip, GELFCompressionNone, GELFCompressionZlib:
+	default:
+		err = fmt.Errorf("unknown gelf compression %s", c.GELFCompression)
+		return
+	}
+
+	// Dial
+	if err = w.dial(); err != nil {
+		return
+	}
+	return
+}
+
+func (w *gelfWriter) network() string {
+	if w.c.GELFNetwork == "" {
+		return GELFNetworkUDP
+	}
+	return w.c.GELFNetwork
+}
+
+func (w *gelfWriter) dial() (err error) {
+	// Check network
+	switch w.network() {
+	case GELFNetworkTCP, GELFNetworkUDP:
+	default:
+		err = fmt.Errorf("unknown gelf network %s", w.network())
+		return
+	}
+
+	// Dial
+	if w.conn, err = net.DialTimeout(w.network(), w.c.GELFAddress, gelfTimeout); err != nil {
+		w.conn = nil
+		err = fmt.Errorf("dialing %s failed: %w", w.c.GELFAddress, err)
+		return
+	}
+	return
+}
+
+// Close implements the io.Closer interface
+func (w *gelfWriter) Close() error {
+	w.m.Lock()
+	defer w.m.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// Write implements the io.Writer interface
+func (w *gelfWriter) Write(p []byte) (n int, err error) {
+	// Lock
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	// Remove newline
+	b := bytes.TrimRight(p, "\n")
+
+	// Send and reconnect once if the connection has been dropped
+	if err = w.send(b); err != nil {
+		// Error is not related to the connection
+		if w.conn != nil {
+			return
+		}
+
+		// Reconnect
+		if errDial := w.dial(); errDial != nil {
+			err = fmt.Errorf("reconnecting failed: %w", errDial)
+			return
+		}
+		if err = w.send(b); err != nil {
+			return
+		}
+	}
+	n = len(p)
+	return
+}
+
+func (w *gelfWriter) send(b []byte) (err error) {
+	// No connection
+	if w.conn == nil {
+		return errors.New("not connected")
+	}
+
+	// Send
+	if w.network() == GELFNetworkTCP {
+		return w.sendTCP(b)
+	}
+	return w.sendUDP(b)
+}
+
+// write writes to the connection and drops it if it failed
+func (w *gelfWriter) write(b []byte) (err error) {
+	// Make sure to close the connection on error
+	defer func() {
+		if err != nil {
+			w.conn.Close()
+			w.conn = nil
+		}
+	}()
+
+	// Write
+	if err = w.conn.SetWriteDeadline(time.Now().Add(gelfTimeout)); err != nil {
+		return
+	}
+	_, err = w.conn.Write(b)
+	return
+}
+
+func (w *gelfWriter) sendTCP(b []byte) (err error) {
+	// Messages are delimited by a null byte
+	m := make([]byte, len(b)+1)
+	copy(m, b)
+
+	// Write
+	if err = w.write(m); err != nil {
+		err = fmt.Errorf("writing failed: %w", err)
+		return
+	}
+	return
+}
+
+func (w *gelfWriter) sendUDP(b []byte) (err error) {
+	// Compress
+	if b, err = w.compress(b); err != nil {
+		err = fmt.Errorf("compressing failed: %w", err)
+		return
+	}
+
+	// Get chunk size
+	chunkSize := w.c.GELFChunkSize
+	if chunkSize <= 0 {
+		chunkSize = gelfDefaultChunkSize
+	}
+
+	// No need to chunk
+	if len(b) <= chunkSize {
+		if err = w.write(b); err != nil {
+			err = fmt.Errorf("writing failed: %w", err)
+			return
+		}
+		return
+	}
+
+	// Get number of chunks
+	dataSize := chunkSize - gelfChunkHeaderSize
+	if dataSize <= 0 {
+		err = fmt.Errorf("gelf chunk size %d is too small", chunkSize)
+		return
+	}
+	count := (len(b) + dataSize - 1) / dataSize
+	if count > gelfMaxChunks {
+		err = fmt.Errorf("gelf message needs %d chunks which is more than %d", count, gelfMaxChunks)
+		return
+	}
+
+	// Create message id
+	id := make([]byte, 8)
+	if _, err = rand.Read(id); err != nil {
+		err = fmt.Errorf("creating message id failed: %w", err)
+		return
+	}
+
+	// Loop through chunks
+	c := make([]byte, 0, chunkSize)
+	for i := 0; i < count; i++ {
+		// Get boundaries
+		from := i * dataSize
+		to := from + dataSize
+		if to > len(b) {
+			to = len(b)
+		}
+
+		// Build chunk
+		c = append(c[:0], gelfChunkMagicBytes...)
+		c = append(c, id...)
+		c = append(c, byte(i), byte(count))
+		c = append(c, b[from:to]...)
+
+		// Write
+		if err = w.write(c); err != nil {
+			err = fmt.Errorf("writing chunk %d failed: %w", i, err)
+			return
+		}
+	}
+	return
+}
+
+func (w *gelfWriter) compress(i []byte) (o []byte, err error) {
+	// Create writer
+	b := &bytes.Buffer{}
+	var cw io.WriteCloser
+	switch w.c.GELFCompression {
+	case GELFCompressionNone:
+		return i, nil
+	case GELFCompressionZlib:
+		cw = zlib.NewWriter(b)
+	default:
+		cw = gzip.NewWriter(b)
+	}
+
+	// Write
+	if _, err = cw.Write(i); err != nil {
+		err = fmt.Errorf("writing failed: %w", err)
+		return
+	}
+
+	// Close
+	if err = cw.Close(); err != nil {
+		err = fmt.Errorf("closing failed: %w", err)
+		return
+	}
+	o = b.Bytes()
+	return
+}
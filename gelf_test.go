@@ -0,0 +1,223 @@
+package astilog
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astikit"
+)
+
+func TestGELFFormatter(t *testing.T) {
+	oldNow := now
+	defer func() { now = oldNow }()
+	now = func() time.Time { return time.Unix(5, 123456789).UTC() }
+
+	f := newGELFFormatter()
+	f.host = "host"
+	if e, g := []byte(`{"__id":"1","_a_b":"v","_b":"true","_e":"err","_i":2,"host":"host","level":3,"short_message":"msg","timestamp":5.123,"version":"1.1"}`+"\n"), testFormat(t, f, "msg", astikit.LoggerLevelError, map[string]interface{}{
+		"a b": "v",
+		"b":   true,
+		"e":   errors.New("err"),
+		"i":   2,
+		"id":  "1",
+	}); !bytes.Equal(e, g) {
+		t.Errorf("expected %s, got %s", e, g)
+	}
+}
+
+func TestGELFWriterUDP(t *testing.T) {
+	// Listen
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(fmt.Errorf("listening failed: %w", err))
+	}
+	defer conn.Close()
+
+	// Read helper
+	read := func() []byte {
+		b := make([]byte, 2048)
+		conn.SetReadDeadline(time.Now().Add(time.Second)) //nolint: errcheck
+		n, _, err := conn.ReadFrom(b)
+		if err != nil {
+			t.Fatal(fmt.Errorf("reading failed: %w", err))
+		}
+		return b[:n]
+	}
+
+	// Gzip
+	w, err := newGELFWriter(Configuration{GELFAddress: conn.LocalAddr().String()})
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating writer failed: %w", err))
+	}
+	defer w.Close()
+	w.Write([]byte(`{"short_message":"gzip"}` + "\n")) //nolint: errcheck
+	r, err := gzip.NewReader(bytes.NewReader(read()))
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating gzip reader failed: %w", err))
+	}
+	if b, _ := ioutil.ReadAll(r); string(b) != `{"short_message":"gzip"}` {
+		t.Errorf("expected gzip message, got %s", b)
+	}
+
+	// Zlib
+	w, err = newGELFWriter(Configuration{
+		GELFAddress:     conn.LocalAddr().String(),
+		GELFCompression: GELFCompressionZlib,
+	})
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating writer failed: %w", err))
+	}
+	defer w.Close()
+	w.Write([]byte(`{"short_message":"zlib"}`)) //nolint: errcheck
+	r2, err := zlib.NewReader(bytes.NewReader(read()))
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating zlib reader failed: %w", err))
+	}
+	if b, _ := ioutil.ReadAll(r2); string(b) != `{"short_message":"zlib"}` {
+		t.Errorf("expected zlib message, got %s", b)
+	}
+
+	// Chunks
+	w, err = newGELFWriter(Configuration{
+		GELFAddress:     conn.LocalAddr().String(),
+		GELFChunkSize:   32,
+		GELFCompression: GELFCompressionNone,
+	})
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating writer failed: %w", err))
+	}
+	defer w.Close()
+	m := `{"short_message":"` + strings.Repeat("a", 50) + `"}`
+	w.Write([]byte(m)) //nolint: errcheck
+	var id []byte
+	var g []byte
+	for i := 0; i < 4; i++ {
+		c := read()
+		if !bytes.Equal(gelfChunkMagicBytes, c[:2]) {
+			t.Errorf("invalid magic bytes %x", c[:2])
+		}
+		if id == nil {
+			id = c[2:10]
+		} else if !bytes.Equal(id, c[2:10]) {
+			t.Errorf("expected id %x, got %x", id, c[2:10])
+		}
+		if e, g := []byte{byte(i), 4}, c[10:12]; !bytes.Equal(e, g) {
+			t.Errorf("expected %v, got %v", e, g)
+		}
+		if len(c) > 32 {
+			t.Errorf("expected chunk size <= 32, got %d", len(c))
+		}
+		g = append(g, c[12:]...)
+	}
+	if m != string(g) {
+		t.Errorf("expected %s, got %s", m, g)
+	}
+
+	// Too many chunks
+	c := w.conn
+	if _, err = w.Write([]byte(strings.Repeat("a", 20*129))); err == nil {
+		t.Error("expected error, got nil")
+	}
+
+	// Connection has been kept
+	if w.conn != c {
+		t.Error("expected connection to be kept")
+	}
+	w.Write([]byte(`{"a":1}`)) //nolint: errcheck
+	if e, g := `{"a":1}`, string(read()); e != g {
+		t.Errorf("expected %s, got %s", e, g)
+	}
+
+	// Chunk size too small
+	w, err = newGELFWriter(Configuration{
+		GELFAddress:     conn.LocalAddr().String(),
+		GELFChunkSize:   gelfChunkHeaderSize,
+		GELFCompression: GELFCompressionNone,
+	})
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating writer failed: %w", err))
+	}
+	defer w.Close()
+	c = w.conn
+	if _, err = w.Write([]byte(m)); err == nil {
+		t.Error("expected error, got nil")
+	}
+	if w.conn != c {
+		t.Error("expected connection to be kept")
+	}
+}
+
+func TestGELFWriterTCP(t *testing.T) {
+	// Listen
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(fmt.Errorf("listening failed: %w", err))
+	}
+	defer ln.Close()
+
+	// Accept
+	ch := make(chan []map[string]interface{})
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var ms []map[string]interface{}
+		r := bufio.NewReader(conn)
+		for i := 0; i < 2; i++ {
+			b, err := r.ReadBytes(0)
+			if err != nil {
+				break
+			}
+			var m map[string]interface{}
+			if err = json.Unmarshal(b[:len(b)-1], &m); err != nil {
+				break
+			}
+			ms = append(ms, m)
+		}
+		ch <- ms
+	}()
+
+	// Create logger
+	l := New(Configuration{
+		GELFAddress: ln.Addr().String(),
+		GELFNetwork: GELFNetworkTCP,
+		Out:         OutGELF,
+	})
+	defer l.Close()
+
+	// Write
+	l.Info("info")
+	l.WarnC(ContextWithField(context.Background(), "k", "v"), "warn")
+
+	// Assert
+	ms := <-ch
+	if e, g := 2, len(ms); e != g {
+		t.Fatalf("expected %d, got %d", e, g)
+	}
+	for idx, e := range []map[string]interface{}{
+		{"level": float64(6), "short_message": "info"},
+		{"_k": "v", "level": float64(4), "short_message": "warn"},
+	} {
+		g := make(map[string]interface{})
+		for k := range e {
+			g[k] = ms[idx][k]
+		}
+		if !reflect.DeepEqual(e, g) {
+			t.Errorf("expected %+v, got %+v", e, g)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package astilog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+const httpDefaultTimeout = 10 * time.Second
+
+// httpClient sends requests to HTTP based outputs
+type httpClient struct {
+	c       *http.Client
+	headers map[string]string
+}
+
+func newHTTPClient(c Configuration) *httpClient {
+	return &httpClient{
+		c:       &http.Client{Timeout: httpDefaultTimeout},
+		headers: c.HTTPHeaders,
+	}
+}
+
+// httpError is returned when the server replied with an unexpected status code
+type httpError struct {
+	body       []byte
+	statusCode int
+}
+
+func (e httpError) Error() string {
+	if b := bytes.TrimSpace(e.body); len(b) > 0 {
+		return fmt.Sprintf("invalid status code %d: %s", e.statusCode, b)
+	}
+	return fmt.Sprintf("invalid status code %d", e.statusCode)
+}
+
+// retryable returns whether the request can be retried
+func (e httpError) retryable() bool {
+	return e.statusCode == http.StatusTooManyRequests || e.statusCode >= 500
+}
+
+// do sends the request and returns the response body. If the request failed, the
+// returned error indicates whether it can be retried.
+func (c *httpClient) do(method, url, contentType string, body []byte, headers map[string]string) (b []byte, retryable bool, err error) {
+	// Create request
+	var req *http.Request
+	if req, err = http.NewRequest(method, url, bytes.NewReader(body)); err != nil {
+		err = fmt.Errorf("creating request failed: %w", err)
+		return
+	}
+
+	// Add headers
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+
+	// Send request
+	var resp *http.Response
+	if resp, err = c.c.Do(req); err != nil {
+		retryable = true
+		err = fmt.Errorf("sending request failed: %w", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	// Only the beginning of error bodies is read
+	r := io.Reader(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		r = io.LimitReader(r, 1<<20)
+	}
+
+	// Read body
+	if b, err = ioutil.ReadAll(r); err != nil {
+		retryable = true
+		err = fmt.Errorf("reading body failed: %w", err)
+		return
+	}
+
+	// Check status code
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		e := httpError{
+			body:       b,
+			statusCode: resp.StatusCode,
+		}
+		retryable = e.retryable()
+		err = e
+		return
+	}
+	return
+}
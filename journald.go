@@ -0,0 +1,199 @@
+// +build !windows
+
+package astilog
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/asticode/go-astikit"
+)
+
+var journaldSocketPath = "/run/systemd/journal/socket"
+
+// Fields set by the writer. Fields colliding with them are prefixed with "FIELD_".
+var journaldReservedFields = map[string]bool{
+	"MESSAGE":           true,
+	"PRIORITY":          true,
+	"SYSLOG_IDENTIFIER": true,
+}
+
+// journaldWriter sends entries to the journal native socket using the journal
+// export format
+type journaldWriter struct {
+	addr *net.UnixAddr
+	c    Configuration
+	conn *net.UnixConn
+}
+
+var newJournaldWriter = func(c Configuration) (io.WriteCloser, error) {
+	// Make sure the socket exists
+	if _, err := os.Stat(journaldSocketPath); err != nil {
+		return nil, fmt.Errorf("stating %s failed: %w", journaldSocketPath, err)
+	}
+
+	// Socket is not connected so that file descriptors can be sent as well
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("creating socket failed: %w", err)
+	}
+	return &journaldWriter{
+		addr: &net.UnixAddr{Name: journaldSocketPath, Net: "unixgram"},
+		c:    c,
+		conn: conn,
+	}, nil
+}
+
+// Close implements the io.Closer interface
+func (w *journaldWriter) Close() error {
+	return w.conn.Close()
+}
+
+// Write implements the io.Writer interface
+func (w *journaldWriter) Write(p []byte) (int, error) {
+	if err := w.writeEntry(entry{
+		l:   astikit.LoggerLevelInfo,
+		msg: string(bytes.TrimRight(p, "\n")),
+	}, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// buildsPayload implements the payloadBuilder interface
+func (w *journaldWriter) buildsPayload() bool {
+	return true
+}
+
+func (w *journaldWriter) writeEntry(e entry, _ []byte) (err error) {
+	// Build payload
+	b := w.payload(e)
+
+	// Send
+	if _, _, err = w.conn.WriteMsgUnix(b, nil, w.addr); err != nil {
+		// Entry is too big for a datagram
+		if !errors.Is(err, syscall.EMSGSIZE) && !errors.Is(err, syscall.ENOBUFS) {
+			err = fmt.Errorf("writing failed: %w", err)
+			return
+		}
+
+		// Send payload in a file instead
+		if err = w.sendFile(b); err != nil {
+			err = fmt.Errorf("sending file failed: %w", err)
+			return
+		}
+	}
+
+	// Count
+	e.cs.written(len(b), nil)
+	return
+}
+
+func (w *journaldWriter) payload(e entry) []byte {
+	// Add default fields
+	b := &bytes.Buffer{}
+	journaldAppendField(b, "MESSAGE", e.msg)
+	journaldAppendField(b, "PRIORITY", strconv.Itoa(syslogSeverity(e.l)))
+	if w.c.AppName != "" {
+		journaldAppendField(b, "SYSLOG_IDENTIFIER", w.c.AppName)
+	}
+
+	// Sort keys
+	var ks []string
+	for k := range e.fs {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+
+	// Add fields
+	for _, k := range ks {
+		n := journaldFieldName(k)
+		if journaldReservedFields[n] {
+			n = journaldFieldName("field_" + n)
+		}
+		if n != "" {
+			journaldAppendField(b, n, fmt.Sprintf("%v", e.fs[k]))
+		}
+	}
+	return b.Bytes()
+}
+
+// sendFile writes the payload in a file that is unlinked right away and sends its file
+// descriptor. Files must be in a tmpfs so that the journal accepts them.
+func (w *journaldWriter) sendFile(b []byte) (err error) {
+	// Get dir
+	dir := "/dev/shm"
+	if _, errStat := os.Stat(dir); errStat != nil {
+		dir = os.TempDir()
+	}
+
+	// Create file
+	var f *os.File
+	if f, err = ioutil.TempFile(dir, "astilog-journald-"); err != nil {
+		err = fmt.Errorf("creating temp file failed: %w", err)
+		return
+	}
+	defer f.Close()
+
+	// Unlink
+	if err = os.Remove(f.Name()); err != nil {
+		err = fmt.Errorf("removing %s failed: %w", f.Name(), err)
+		return
+	}
+
+	// Write
+	if _, err = f.Write(b); err != nil {
+		err = fmt.Errorf("writing in %s failed: %w", f.Name(), err)
+		return
+	}
+
+	// Send file descriptor
+	if _, _, err = w.conn.WriteMsgUnix(nil, syscall.UnixRights(int(f.Fd())), w.addr); err != nil {
+		err = fmt.Errorf("writing file descriptor failed: %w", err)
+		return
+	}
+	return
+}
+
+func journaldAppendField(b *bytes.Buffer, name, value string) {
+	// Value doesn't contain new lines
+	if !strings.Contains(value, "\n") {
+		b.WriteString(name + "=" + value + "\n")
+		return
+	}
+
+	// Value is prefixed with its size
+	b.WriteString(name + "\n")
+	binary.Write(b, binary.LittleEndian, uint64(len(value))) //nolint: errcheck
+	b.WriteString(value + "\n")
+}
+
+// journaldFieldName returns a valid journal field name: it only contains uppercase
+// letters, digits and underscores, doesn't start with an underscore or a digit and is
+// at most 64 characters long
+func journaldFieldName(k string) string {
+	// Replace invalid characters
+	o := []byte(strings.ToUpper(k))
+	for idx, c := range o {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			o[idx] = '_'
+		}
+	}
+
+	// Remove invalid first characters
+	n := strings.TrimLeft(string(o), "_0123456789")
+	if len(n) > 64 {
+		n = n[:64]
+	}
+	return n
+}
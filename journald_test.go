@@ -0,0 +1,145 @@
+// +build !windows
+
+package astilog
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astikit"
+)
+
+func TestJournaldFieldName(t *testing.T) {
+	for _, v := range []struct {
+		e, i string
+	}{
+		{e: "KEY", i: "key"},
+		{e: "APP_NAME", i: "app_name"},
+		{e: "A_B_C", i: "a.b-c"},
+		{e: "KEY2", i: "_1key2"},
+		{e: "", i: "__"},
+		{e: strings.Repeat("A", 64), i: strings.Repeat("a", 70)},
+	} {
+		if g := journaldFieldName(v.i); v.e != g {
+			t.Errorf("expected %s, got %s", v.e, g)
+		}
+	}
+}
+
+func TestJournaldWriter(t *testing.T) {
+	// Create temp dir
+	d, err := ioutil.TempDir("", "astilog_")
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating temp dir failed: %w", err))
+	}
+
+	// Make sure to delete directory
+	defer os.RemoveAll(d)
+
+	// Listen
+	p := filepath.Join(d, "journal.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: p, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(fmt.Errorf("listening failed: %w", err))
+	}
+	defer conn.Close()
+
+	// Bypass socket path
+	old := journaldSocketPath
+	journaldSocketPath = p
+	defer func() { journaldSocketPath = old }()
+
+	// Create logger
+	l := New(Configuration{
+		AppName: "app",
+		Out:     OutJournald,
+	})
+	defer l.Close()
+	switch tp := l.ss[0].w.(type) {
+	case *journaldWriter:
+	default:
+		t.Fatalf("expected *journaldWriter, got %T", tp)
+	}
+
+	// Read helper
+	read := func() []byte {
+		b := make([]byte, 1024)
+		oob := make([]byte, 1024)
+		conn.SetReadDeadline(time.Now().Add(time.Second)) //nolint: errcheck
+		n, oobn, _, _, err := conn.ReadMsgUnix(b, oob)
+		if err != nil {
+			t.Fatal(fmt.Errorf("reading failed: %w", err))
+		}
+
+		// No file descriptor
+		if oobn == 0 {
+			return b[:n]
+		}
+
+		// Parse file descriptor
+		ms, err := syscall.ParseSocketControlMessage(oob[:oobn])
+		if err != nil {
+			t.Fatal(fmt.Errorf("parsing control message failed: %w", err))
+		}
+		fds, err := syscall.ParseUnixRights(&ms[0])
+		if err != nil {
+			t.Fatal(fmt.Errorf("parsing unix rights failed: %w", err))
+		}
+		f := os.NewFile(uintptr(fds[0]), "journald")
+		defer f.Close()
+		if _, err = f.Seek(0, 0); err != nil {
+			t.Fatal(fmt.Errorf("seeking failed: %w", err))
+		}
+		b, err = ioutil.ReadAll(f)
+		if err != nil {
+			t.Fatal(fmt.Errorf("reading file failed: %w", err))
+		}
+		return b
+	}
+
+	// Datagram
+	l.WarnC(ContextWithFields(context.Background(), map[string]interface{}{
+		"a.b": 1,
+		"c":   "multi\nline",
+	}), "msg")
+	e := &bytes.Buffer{}
+	e.WriteString("MESSAGE=msg\nPRIORITY=4\nSYSLOG_IDENTIFIER=app\nA_B=1\nAPP_NAME=app\nC\n")
+	binary.Write(e, binary.LittleEndian, uint64(10)) //nolint: errcheck
+	e.WriteString("multi\nline\n")
+	if g := read(); !bytes.Equal(e.Bytes(), g) {
+		t.Errorf("expected %q, got %q", e.Bytes(), g)
+	}
+
+	// File
+	m := strings.Repeat("a", 1<<20)
+	l.Write(astikit.LoggerLevelError, m)
+	if e, g := "MESSAGE="+m+"\nPRIORITY=3\nSYSLOG_IDENTIFIER=app\nAPP_NAME=app\n", string(read()); e != g {
+		t.Errorf("expected %d bytes, got %d", len(e), len(g))
+	}
+}
+
+func TestJournaldWriterPayloadReservedFields(t *testing.T) {
+	w := &journaldWriter{c: Configuration{AppName: "app"}}
+	if e, g := "MESSAGE=msg\nPRIORITY=6\nSYSLOG_IDENTIFIER=app\nK=v\nFIELD_MESSAGE=m\nFIELD_PRIORITY=p\nFIELD_SYSLOG_IDENTIFIER=s\n", string(w.payload(entry{
+		fs: map[string]interface{}{
+			"k":                 "v",
+			"message":           "m",
+			"priority":          "p",
+			"syslog_identifier": "s",
+		},
+		l:   astikit.LoggerLevelInfo,
+		msg: "msg",
+	})); e != g {
+		t.Errorf("expected %q, got %q", e, g)
+	}
+}
@@ -0,0 +1,199 @@
+package astilog
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// logfmtFormatter writes entries as logfmt lines: the time, level and message come first
+// and are followed by fields sorted by key. Values are quoted when needed.
+type logfmtFormatter struct {
+	c      Configuration
+	msgKey string
+}
+
+func newLogfmtFormatter(c Configuration) (f *logfmtFormatter) {
+	f = &logfmtFormatter{
+		c:      c,
+		msgKey: "msg",
+	}
+	if c.MessageKey != "" {
+		f.msgKey = c.MessageKey
+	}
+	return
+}
+
+// Format implements the Formatter interface
+func (f *logfmtFormatter) Format(e Entry) (b []byte, err error) {
+	// Add time
+	tf := f.c.TimestampFormat
+	if tf == "" {
+		tf = time.RFC3339Nano
+	}
+	b = logfmtAppend(b, "time", e.Time.Format(tf))
+
+	// Add level
+	b = append(b, ' ')
+	b = logfmtAppend(b, "level", e.Level.String())
+
+	// Add msg
+	b = append(b, ' ')
+	b = logfmtAppend(b, f.msgKey, e.Message)
+
+	// Get field names
+	ns := make(map[string]string, len(e.Fields))
+	ks := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		n := f.fieldName(e.Fields, k)
+		ns[n] = k
+		ks = append(ks, n)
+	}
+
+	// Sort fields
+	sort.Strings(ks)
+
+	// Add fields
+	for _, n := range ks {
+		b = append(b, ' ')
+		b = logfmtAppend(b, n, logfmtValue(e.Fields[ns[n]]))
+	}
+
+	// Add newline
+	b = append(b, newLine...)
+	return
+}
+
+// fieldName returns the key of the field. Time, level and msg can't be overwritten,
+// colliding fields are written under "fields." instead.
+func (f *logfmtFormatter) fieldName(fs map[string]interface{}, k string) (n string) {
+	n = k
+	for {
+		if n != "time" && n != "level" && n != f.msgKey {
+			if _, ok := fs[n]; n == k || !ok {
+				return
+			}
+		}
+		n = "fields." + n
+	}
+}
+
+func logfmtValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func logfmtAppend(b []byte, k, v string) []byte {
+	b = append(b, logfmtKey(k)...)
+	b = append(b, '=')
+	if logfmtNeedsQuoting(v) {
+		return strconv.AppendQuote(b, v)
+	}
+	return append(b, v...)
+}
+
+// logfmtKey replaces characters that are not allowed in keys with "_"
+func logfmtKey(k string) string {
+	if k == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError || r == 0x7f {
+			return '_'
+		}
+		return r
+	}, k)
+}
+
+func logfmtNeedsQuoting(v string) bool {
+	if v == "" {
+		return true
+	}
+	for _, r := range v {
+		if r <= ' ' || r == '=' || r == '"' || r == '\\' || r == utf8.RuneError || r == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+// logfmtPair represents a key/value pair of a logfmt line
+type logfmtPair struct {
+	k string
+	v string
+}
+
+// parseLogfmt parses a logfmt line. Keys without values get an empty value.
+func parseLogfmt(line string) (ps []logfmtPair, err error) {
+	for i := 0; i < len(line); {
+		// Skip spaces
+		if line[i] == ' ' || line[i] == '\t' || line[i] == '\n' || line[i] == '\r' {
+			i++
+			continue
+		}
+
+		// Get key
+		start := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' && line[i] != '\n' {
+			i++
+		}
+		p := logfmtPair{k: line[start:i]}
+
+		// No value
+		if i >= len(line) || line[i] != '=' {
+			ps = append(ps, p)
+			continue
+		}
+		i++
+
+		// Get value
+		if i < len(line) && line[i] == '"' {
+			// Find closing quote
+			start = i
+			for i++; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(line) {
+				err = fmt.Errorf("unterminated quoted value for key %s", p.k)
+				return
+			}
+			i++
+
+			// Unquote
+			if p.v, err = strconv.Unquote(line[start:i]); err != nil {
+				err = fmt.Errorf("unquoting value for key %s failed: %w", p.k, err)
+				return
+			}
+		} else {
+			start = i
+			for i < len(line) && line[i] != ' ' && line[i] != '\n' {
+				i++
+			}
+			p.v = line[start:i]
+		}
+		ps = append(ps, p)
+	}
+
+	// No pairs
+	if len(ps) == 0 {
+		err = errors.New("no pairs found")
+		return
+	}
+	return
+}
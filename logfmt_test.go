@@ -0,0 +1,142 @@
+package astilog
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astikit"
+)
+
+func TestLogfmtFormatter(t *testing.T) {
+	oldNow := now
+	defer func() { now = oldNow }()
+	now = func() time.Time { return time.Unix(5, 0).UTC() }
+
+	f := newLogfmtFormatter(Configuration{})
+	if e, g := "time=1970-01-01T00:00:05Z level=debug msg=msg fields.level=ignored k_9=v k1=v1 k2=\"a b\" k3=\"a=b\" k4=\"\\\"q\\\"\" k5=\"l1\\nl2\" k6=\"\" k7=2 k8=err level_=v msg_=v\n", string(testFormat(t, f, "msg", astikit.LoggerLevelDebug, map[string]interface{}{
+		"k1":     "v1",
+		"k2":     "a b",
+		"k3":     "a=b",
+		"k4":     `"q"`,
+		"k5":     "l1\nl2",
+		"k6":     "",
+		"k7":     2,
+		"k8":     errors.New("err"),
+		"k 9":    "v",
+		"level":  "ignored",
+		"level ": "v",
+		"msg=":   "v",
+	})); e != g {
+		t.Errorf("expected %s, got %s", e, g)
+	}
+
+	f = newLogfmtFormatter(Configuration{
+		MessageKey:      "message",
+		TimestampFormat: "2006",
+	})
+	if e, g := "time=1970 level=info message=\"a message\"\n", string(testFormat(t, f, "a message", astikit.LoggerLevelInfo, map[string]interface{}{})); e != g {
+		t.Errorf("expected %s, got %s", e, g)
+	}
+}
+
+func TestLogfmtRoundTrip(t *testing.T) {
+	oldNow := now
+	defer func() { now = oldNow }()
+	now = func() time.Time { return time.Unix(5, 123).UTC() }
+
+	// Loop through values
+	f := newLogfmtFormatter(Configuration{})
+	for _, v := range []string{
+		"",
+		"simple",
+		"with space",
+		"with=equal",
+		`with "quotes"`,
+		`with \ backslash`,
+		"with\nnewline\r\tand tab",
+		"with unicode éà 日本",
+		"with control \x00\x1b char",
+		" leading and trailing ",
+		`\"`,
+		"=",
+		`"`,
+	} {
+		// Format
+		b := testFormat(t, f, v, astikit.LoggerLevelError, map[string]interface{}{"k": v})
+
+		// Parse
+		ps, err := parseLogfmt(string(b))
+		if err != nil {
+			t.Fatal(fmt.Errorf("parsing %q failed: %w", b, err))
+		}
+		if e, g := []logfmtPair{
+			{k: "time", v: "1970-01-01T00:00:05.000000123Z"},
+			{k: "level", v: "error"},
+			{k: "msg", v: v},
+			{k: "k", v: v},
+		}, ps; !reflect.DeepEqual(e, g) {
+			t.Errorf("expected %+v, got %+v for %q", e, g, b)
+		}
+	}
+}
+
+func TestParseLogfmt(t *testing.T) {
+	// Valid
+	ps, err := parseLogfmt("a=1 b=\"2 3\"  c d= e=\"\"\n")
+	if err != nil {
+		t.Fatal(fmt.Errorf("parsing failed: %w", err))
+	}
+	if e, g := []logfmtPair{
+		{k: "a", v: "1"},
+		{k: "b", v: "2 3"},
+		{k: "c"},
+		{k: "d"},
+		{k: "e"},
+	}, ps; !reflect.DeepEqual(e, g) {
+		t.Errorf("expected %+v, got %+v", e, g)
+	}
+
+	// Invalid
+	for _, l := range []string{"", "a=\"1", "a=\"\\z\""} {
+		if _, err = parseLogfmt(l); err == nil {
+			t.Errorf("expected error for %q", l)
+		}
+	}
+}
+
+func TestLogfmtRoundTripCollidingFields(t *testing.T) {
+	oldNow := now
+	defer func() { now = oldNow }()
+	now = func() time.Time { return time.Unix(5, 0).UTC() }
+
+	// Format
+	f := newLogfmtFormatter(Configuration{MessageKey: "message"})
+	b := testFormat(t, f, "m", astikit.LoggerLevelWarn, map[string]interface{}{
+		"fields.time": "v1",
+		"level":       "v2",
+		"message":     "v3",
+		"msg":         "v4",
+		"time":        "v5",
+	})
+
+	// Parse
+	ps, err := parseLogfmt(string(b))
+	if err != nil {
+		t.Fatal(fmt.Errorf("parsing %q failed: %w", b, err))
+	}
+	if e, g := []logfmtPair{
+		{k: "time", v: "1970-01-01T00:00:05Z"},
+		{k: "level", v: "warn"},
+		{k: "message", v: "m"},
+		{k: "fields.fields.time", v: "v5"},
+		{k: "fields.level", v: "v2"},
+		{k: "fields.message", v: "v3"},
+		{k: "fields.time", v: "v1"},
+		{k: "msg", v: "v4"},
+	}, ps; !reflect.DeepEqual(e, g) {
+		t.Errorf("expected %+v, got %+v for %q", e, g, b)
+	}
+}
@@ -1,11 +1,8 @@
 package astilog
 
 import (
-	"bytes"
 	"context"
 	"fmt"
-	"io"
-	"log"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -23,11 +20,11 @@ var newLine = []byte("\n")
 type Logger struct {
 	c         Configuration
 	createdAt time.Time
-	f         formatter
 	fs        map[string]interface{}
 	mf        *sync.RWMutex       // Locks fs
 	l         astikit.LoggerLevel // Level
-	w         io.WriteCloser
+	ss        []*sink
+	stopSigs  func()
 }
 
 // NewFromFlags creates a new Logger based on flags
@@ -52,73 +49,150 @@ func New(c Configuration) (l *Logger) {
 		l.WithField("app_name", c.AppName)
 	}
 
-	// Set writer
-	l.setWriter(c)
-
 	// Set level
 	l.setLevel(c)
 
-	// Set formatter
-	l.setFormatter(c, l.createdAt)
+	// Set sinks
+	l.setSinks(c)
+
+	// Handle signals
+	if c.ReopenOnSIGHUP {
+		l.stopSigs = l.handleReopenSignal()
+	}
 	return
 }
 
 // Close closes the logger properly
 func (l *Logger) Close() error {
-	return l.w.Close()
+	// Stop handling signals
+	if l.stopSigs != nil {
+		l.stopSigs()
+	}
+
+	// Close sinks
+	errs := astikit.NewErrors()
+	for _, s := range l.ss {
+		errs.Add(s.w.Close())
+	}
+	if !errs.IsNil() {
+		return errs
+	}
+	return nil
 }
 
-func (l *Logger) setWriter(c Configuration) {
-	// File
-	if c.Filename != "" {
-		// Open file
-		f, err := os.OpenFile(c.Filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0755)
-		if err == nil {
-			l.w = f
-			return
-		}
+type reopener interface {
+	Reopen() error
+}
 
-		// Revert to default
-		c.Out = ""
-		log.Println(fmt.Errorf("astilog: creating %s failed: %w", c.Filename, err))
+// Reopen reopens the log file, if any, without dropping concurrent writes. It should be
+// called when the file has been moved by an external tool such as logrotate.
+func (l *Logger) Reopen() error {
+	errs := astikit.NewErrors()
+	for _, s := range l.ss {
+		if r, ok := s.w.(reopener); ok {
+			errs.Add(r.Reopen())
+		}
 	}
+	if !errs.IsNil() {
+		return errs
+	}
+	return nil
+}
 
-	// Syslog
-	if c.Out == OutSyslog {
-		// Create
-		s, err := newSyslogWriter(c)
-		if err == nil {
-			l.w = s
-			return
+type flusher interface {
+	Flush() error
+}
+
+// Flush blocks until all entries written asynchronously have been written
+func (l *Logger) Flush() error {
+	errs := astikit.NewErrors()
+	for _, s := range l.ss {
+		if f, ok := s.w.(flusher); ok {
+			errs.Add(f.Flush())
 		}
+	}
+	if !errs.IsNil() {
+		return errs
+	}
+	return nil
+}
+
+type syncer interface {
+	Sync() error
+}
 
-		// Revert to default
-		c.Out = ""
-		log.Println(fmt.Errorf("astilog: creating syslog failed: %w", err))
+const defaultSyncTimeout = 5 * time.Second
+
+// Sync flushes all entries written asynchronously and commits them to stable storage
+// when possible. It returns an error if it hasn't completed before the sync timeout so
+// that a hung sink can't block the caller forever.
+func (l *Logger) Sync() error {
+	// Get timeout
+	timeout := l.c.SyncTimeout
+	if timeout <= 0 {
+		timeout = defaultSyncTimeout
 	}
 
-	// Stderr
-	if c.Out == OutStderr {
-		l.w = astikit.NopCloser(os.Stderr)
-		return
+	// Sync in a goroutine
+	ch := make(chan error, 1)
+	go func() { ch <- l.sync() }()
+
+	// Wait
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case err := <-ch:
+		return err
+	case <-t.C:
+		return fmt.Errorf("astilog: syncing timed out after %s", timeout)
 	}
+}
 
-	// Default is stdout
-	l.w = astikit.NopCloser(os.Stdout)
+func (l *Logger) sync() error {
+	errs := astikit.NewErrors()
+	for _, s := range l.ss {
+		if sc, ok := s.w.(syncer); ok {
+			errs.Add(sc.Sync())
+		} else if f, ok := s.w.(flusher); ok {
+			errs.Add(f.Flush())
+		}
+	}
+	if !errs.IsNil() {
+		return errs
+	}
+	return nil
+}
+
+type dropper interface {
+	Dropped() uint64
+}
+
+// Dropped returns the number of entries that have been dropped because an asynchronous
+// queue was full
+func (l *Logger) Dropped() (n uint64) {
+	for _, s := range l.ss {
+		if d, ok := s.w.(dropper); ok {
+			n += d.Dropped()
+		}
+	}
+	return
 }
 
 func (l *Logger) setLevel(c Configuration) {
 	l.l = c.Level
 }
 
-func (l *Logger) setFormatter(c Configuration, createdAt time.Time) {
-	switch c.Format {
-	case FormatJSON:
-		l.f = newJSONFormatter(c, createdAt)
-	case FormatMinimalist:
-		l.f = newMinimalistFormatter()
-	default:
-		l.f = newTextFormatter(c, createdAt)
+func (l *Logger) setSinks(c Configuration) {
+	// No sinks means the configuration is the sink
+	if len(c.Sinks) == 0 {
+		l.ss = []*sink{newSink(c, l.createdAt)}
+		return
+	}
+
+	// Loop through sinks
+	l.ss = []*sink{}
+	for _, sc := range c.Sinks {
+		l.ss = append(l.ss, newSink(sinkConfiguration(c, sc), l.createdAt))
 	}
 }
 
@@ -156,8 +230,10 @@ func (l *Logger) write(ctx context.Context, msgFunc func() string, lvl astikit.L
 	l.mf.RUnlock()
 
 	// Add source
+	var src string
 	if l.c.Source {
-		fs["source"] = source()
+		src = source()
+		fs["source"] = src
 	}
 
 	// Add context fields
@@ -169,53 +245,28 @@ func (l *Logger) write(ctx context.Context, msgFunc func() string, lvl astikit.L
 		cfs.m.Unlock()
 	}
 
-	// Format message
-	m := l.f.format(msgFunc(), lvl, fs)
-
-	// Write
-	if l.c.MaxWriteLength > 0 && len(m) > l.c.MaxWriteLength {
-		// Loop
-		var c int
-		for {
-			// Get boundaries
-			from := c * l.c.MaxWriteLength
-			to := (c + 1) * l.c.MaxWriteLength
-
-			// We're done
-			if from > len(m)-1 {
-				break
-			}
-
-			// We've reached the end of the message
-			if to > len(m) {
-				to = len(m)
-			}
-
-			// Since append modifies the input slice, we need to create a new one when
-			// appending a new line
-			wm := m[from:to]
-			if to != len(m) {
-				wm = make([]byte, to-from)
-				copy(wm, m[from:to])
-				if !bytes.HasSuffix(wm, newLine) {
-					wm = append(wm, newLine...)
-				}
-			}
-
-			// Write
-			if _, err := l.w.Write(wm); err != nil {
-				log.Println(fmt.Errorf("astilog: writing failed: %w", err))
-				return
-			}
-
-			// Increment
-			c++
+	// Create entry
+	e := entry{
+		fs:     fs,
+		l:      lvl,
+		msg:    msgFunc(),
+		source: src,
+		t:      now(),
+	}
+
+	// Add trace
+	e.traceID, e.spanID = TraceFromContext(ctx)
+
+	// Loop through sinks
+	for _, s := range l.ss {
+		// Check level
+		if s.l > lvl {
+			continue
 		}
-	} else {
+
 		// Write
-		if _, err := l.w.Write(m); err != nil {
-			log.Println(fmt.Errorf("astilog: writing failed: %w", err))
-			return
+		if err := s.write(e); err != nil {
+			handleError(s.c.ErrorHandler, fmt.Errorf("astilog: %w", err))
 		}
 	}
 }
@@ -302,24 +353,34 @@ func (l *Logger) Errorf(format string, v ...interface{}) {
 
 var exit = func() { os.Exit(1) }
 
+func (l *Logger) exit() {
+	// Make sure the fatal entry is written
+	if err := l.Sync(); err != nil {
+		handleError(l.c.ErrorHandler, fmt.Errorf("astilog: syncing failed: %w", err))
+	}
+
+	// Exit
+	exit()
+}
+
 func (l *Logger) Fatal(v ...interface{}) {
 	l.write(context.Background(), msgFunc(v...), astikit.LoggerLevelFatal)
-	exit()
+	l.exit()
 }
 
 func (l *Logger) FatalC(ctx context.Context, v ...interface{}) {
 	l.write(ctx, msgFunc(v...), astikit.LoggerLevelFatal)
-	exit()
+	l.exit()
 }
 
 func (l *Logger) FatalCf(ctx context.Context, format string, v ...interface{}) {
 	l.write(ctx, msgFuncf(format, v...), astikit.LoggerLevelFatal)
-	exit()
+	l.exit()
 }
 
 func (l *Logger) Fatalf(format string, v ...interface{}) {
 	l.write(context.Background(), msgFuncf(format, v...), astikit.LoggerLevelFatal)
-	exit()
+	l.exit()
 }
 
 func (l *Logger) Write(lv astikit.LoggerLevel, v ...interface{}) {
@@ -3,14 +3,9 @@ package astilog
 import (
 	"bytes"
 	"context"
-	"errors"
-	"fmt"
 	"io"
-	"io/ioutil"
-	"log/syslog"
-	"os"
-	"path/filepath"
 	"reflect"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -25,74 +20,6 @@ func TestNew(t *testing.T) {
 	}
 }
 
-func TestSetWriter(t *testing.T) {
-	// Create temp dir
-	d, err := ioutil.TempDir("", "astilog_")
-	if err != nil {
-		t.Fatal(fmt.Errorf("creating temp dir failed: %w", err))
-	}
-
-	// Make sure to delete directory
-	defer os.RemoveAll(d)
-
-	// Create logger
-	l := NewFromFlags()
-	defer l.Close()
-
-	// Default to stdout
-	if !reflect.DeepEqual(l.w, astikit.NopCloser(os.Stdout)) {
-		t.Error("expected false, got true")
-	}
-
-	// File
-	f := filepath.Join(d, "f1.log")
-	l.setWriter(Configuration{Filename: f})
-	switch tp := l.w.(type) {
-	case *os.File:
-	default:
-		t.Errorf("expected *os.File, got %T", tp)
-	}
-	l.w.Write([]byte("test")) //nolint: errcheck
-	b, err := ioutil.ReadFile(f)
-	if err != nil {
-		t.Fatal(fmt.Errorf("reading %s failed: %w", f, err))
-	}
-	if e := []byte("test"); !bytes.Equal(e, b) {
-		t.Errorf("expected %s, got %s", e, b)
-	}
-
-	// File not working defaults to stdout
-	l.setWriter(Configuration{Filename: filepath.Join("testdata/invalidpath")})
-	if !reflect.DeepEqual(l.w, astikit.NopCloser(os.Stdout)) {
-		t.Error("expected false, got true")
-	}
-
-	// Syslog
-	l.setWriter(Configuration{Out: OutSyslog})
-	switch tp := l.w.(type) {
-	case *syslog.Writer:
-	default:
-		t.Errorf("expected *os.File, got %T", tp)
-	}
-
-	// Bypass newSyslogWriter
-	old := newSyslogWriter
-	newSyslogWriter = func(c Configuration) (io.WriteCloser, error) { return nil, errors.New("dummy") }
-	defer func() { newSyslogWriter = old }()
-
-	// syslog not working defaults to stdout
-	l.setWriter(Configuration{Out: OutSyslog})
-	if !reflect.DeepEqual(l.w, astikit.NopCloser(os.Stdout)) {
-		t.Error("expected false, got true")
-	}
-
-	// Stderr
-	l.setWriter(Configuration{Out: OutStderr})
-	if !reflect.DeepEqual(l.w, astikit.NopCloser(os.Stderr)) {
-		t.Error("expected false, got true")
-	}
-}
-
 func TestSetLevel(t *testing.T) {
 	l := NewFromFlags()
 	defer l.Close()
@@ -118,24 +45,8 @@ func TestSetLevel(t *testing.T) {
 	}
 }
 
-func TestSetFormatter(t *testing.T) {
-	l := NewFromFlags()
-	defer l.Close()
-	switch tp := l.f.(type) {
-	case *textFormatter:
-	default:
-		t.Errorf("expected *textFormatter, got %T", tp)
-	}
-	l.setFormatter(Configuration{Format: FormatJSON}, time.Unix(0, 0))
-	switch tp := l.f.(type) {
-	case *jsonFormatter:
-	default:
-		t.Errorf("expected *jsonFormatter, got %T", tp)
-	}
-}
-
 func TestSource(t *testing.T) {
-	if e, g := "logger_test.go:138", source(); e != g {
+	if e, g := "logger_test.go:49", source(); e != g {
 		t.Errorf("expected %s, got %s", e, g)
 	}
 }
@@ -144,7 +55,7 @@ func TestWrite(t *testing.T) {
 	b := &bytes.Buffer{}
 	l := NewFromFlags()
 	defer l.Close()
-	l.w = astikit.NopCloser(b)
+	l.ss[0].w = astikit.NopCloser(b)
 
 	// Level is not sufficient
 	l.l = astikit.LoggerLevelInfo
@@ -166,13 +77,13 @@ func TestWrite(t *testing.T) {
 	l.c.Source = true
 	l.fs = map[string]interface{}{}
 	l.write(context.Background(), msgFunc("test"), astikit.LoggerLevelInfo)
-	if e, g := " INFO[0000]test  source=logger_test.go:168\n", b.String(); e != g {
+	if e, g := " INFO[0000]test  source=logger_test.go:79\n", b.String(); e != g {
 		t.Errorf("expected %s, got %s", e, g)
 	}
 
 	// Max write length
 	b.Reset()
-	l.c.MaxWriteLength = 3
+	l.ss[0].c.MaxWriteLength = 3
 	l.c.Source = false
 	l.write(context.Background(), msgFunc("testtesttest"), astikit.LoggerLevelInfo)
 	if e, g := " IN\nFO[\n000\n0]t\nest\ntes\ntte\nst\n", b.String(); e != g {
@@ -191,7 +102,7 @@ func TestLogger(t *testing.T) {
 	l := New(Configuration{Level: astikit.LoggerLevelDebug})
 	defer l.Close()
 	b := &bytes.Buffer{}
-	l.w = astikit.NopCloser(b)
+	l.ss[0].w = astikit.NopCloser(b)
 	ctx := ContextWithField(context.Background(), "k", "v")
 
 	// Run
@@ -258,3 +169,64 @@ FATAL[0000]fatal test  k=v
 		t.Errorf("expected %+v, got %+v", fs, g)
 	}
 }
+
+type mockedSyncer struct {
+	io.WriteCloser
+	ch     chan struct{}
+	synced int32
+}
+
+func (s *mockedSyncer) Sync() error {
+	if s.ch != nil {
+		<-s.ch
+	}
+	atomic.StoreInt32(&s.synced, 1)
+	return nil
+}
+
+func TestSync(t *testing.T) {
+	// Bypass exit
+	old := exit
+	count := 0
+	exit = func() { count++ }
+	defer func() { exit = old }()
+
+	// Create logger
+	l := New(Configuration{
+		Format:      FormatMinimalist,
+		SyncTimeout: 10 * time.Millisecond,
+	})
+	defer l.Close()
+	b := &bytes.Buffer{}
+	s := &mockedSyncer{WriteCloser: astikit.NopCloser(b)}
+	l.ss[0].w = s
+
+	// Sync
+	if err := l.Sync(); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	if atomic.LoadInt32(&s.synced) != 1 {
+		t.Error("expected true, got false")
+	}
+
+	// Fatal syncs
+	atomic.StoreInt32(&s.synced, 0)
+	l.Fatal("fatal")
+	if atomic.LoadInt32(&s.synced) != 1 {
+		t.Error("expected true, got false")
+	}
+	if e, g := "fatal\n", b.String(); e != g {
+		t.Errorf("expected %s, got %s", e, g)
+	}
+
+	// Hung sink doesn't block exit
+	s.ch = make(chan struct{})
+	defer close(s.ch)
+	if err := l.Sync(); err == nil {
+		t.Error("expected error, got nil")
+	}
+	l.Fatal("fatal")
+	if e, g := 2, count; e != g {
+		t.Errorf("expected %d, got %d", e, g)
+	}
+}
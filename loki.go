@@ -0,0 +1,189 @@
+package astilog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asticode/go-astikit"
+)
+
+const (
+	lokiDefaultAppName = "astilog"
+	lokiLevelLabel     = "level"
+)
+
+var lokiDefaultLabels = []string{"app_name"}
+
+// lokiWriter sends entries in batches to the Loki push API. Fields listed in the labels
+// are used as stream labels while the other ones are added to the JSON log line.
+type lokiWriter struct {
+	*batchWriter
+	c      Configuration
+	f      *jsonFormatter
+	hc     *httpClient
+	labels []string
+}
+
+func newLokiWriter(c Configuration, createdAt time.Time) (w *lokiWriter, err error) {
+	// Check url
+	if c.LokiURL == "" {
+		err = errors.New("loki url is empty")
+		return
+	}
+
+	// Create
+	w = &lokiWriter{
+		c:      c,
+		f:      newJSONFormatter(c, createdAt),
+		hc:     newHTTPClient(c),
+		labels: c.LokiLabels,
+	}
+
+	// Default labels
+	if len(w.labels) == 0 {
+		w.labels = lokiDefaultLabels
+	}
+
+	// Create batch writer
+	w.batchWriter = newBatchWriter(c, w.send, nil)
+	return
+}
+
+// Write implements the io.Writer interface
+func (w *lokiWriter) Write(p []byte) (int, error) {
+	if err := w.writeEntry(entry{
+		l:   astikit.LoggerLevelInfo,
+		msg: string(bytes.TrimRight(p, "\n")),
+		t:   now(),
+	}, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// buildsPayload implements the payloadBuilder interface
+func (w *lokiWriter) buildsPayload() bool {
+	return true
+}
+
+func (w *lokiWriter) writeEntry(e entry, _ []byte) error {
+	// Remove labels from fields
+	fs := make(map[string]interface{}, len(e.fs))
+	for k, v := range e.fs {
+		fs[k] = v
+	}
+	for _, l := range w.labels {
+		delete(fs, l)
+	}
+
+	// Format
+	fe := e.export()
+	fe.Fields = fs
+	b, err := w.f.Format(fe)
+	if err != nil {
+		return fmt.Errorf("formatting failed: %w", err)
+	}
+
+	// Add
+	return w.add(batchItem{
+		b: bytes.TrimRight(b, "\n"),
+		e: e,
+	})
+}
+
+var lokiInvalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// stream returns the labels of an entry
+func (w *lokiWriter) stream(e entry) map[string]string {
+	s := make(map[string]string)
+	for _, l := range w.labels {
+		// Get value
+		var v string
+		if l == lokiLevelLabel {
+			v = e.l.String()
+		} else if i, ok := e.fs[l]; ok {
+			v = fmt.Sprintf("%v", i)
+		} else if l == "app_name" {
+			v = w.c.AppName
+		}
+		if v == "" {
+			continue
+		}
+
+		// Get name
+		n := lokiInvalidLabelChars.ReplaceAllString(l, "_")
+		if n[0] >= '0' && n[0] <= '9' {
+			n = "_" + n
+		}
+		s[n] = v
+	}
+
+	// Loki requires at least one label
+	if len(s) == 0 {
+		s["app_name"] = lokiDefaultAppName
+	}
+	return s
+}
+
+type lokiPush struct {
+	Streams []*lokiStream `json:"streams"`
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func (w *lokiWriter) send(is []batchItem) (retry, dropped []batchItem, err error) {
+	// Group entries by stream
+	p := lokiPush{}
+	ss := make(map[string]*lokiStream)
+	for _, i := range is {
+		// Get stream
+		s := w.stream(i.e)
+
+		// Get key
+		var ks []string
+		for k, v := range s {
+			ks = append(ks, k+"="+strconv.Quote(v))
+		}
+		sort.Strings(ks)
+		k := strings.Join(ks, ",")
+
+		// Get stream
+		ls, ok := ss[k]
+		if !ok {
+			ls = &lokiStream{Stream: s}
+			ss[k] = ls
+			p.Streams = append(p.Streams, ls)
+		}
+
+		// Add value
+		ls.Values = append(ls.Values, [2]string{strconv.FormatInt(i.e.t.UnixNano(), 10), string(i.b)})
+	}
+
+	// Marshal
+	var b []byte
+	if b, err = json.Marshal(p); err != nil {
+		err = fmt.Errorf("marshaling failed: %w", err)
+		return
+	}
+
+	// Send
+	var retryable bool
+	if _, retryable, err = w.hc.do(http.MethodPost, w.c.LokiURL, "application/json", b, nil); err != nil {
+		if retryable {
+			retry = is
+		}
+		return
+	}
+	return
+}
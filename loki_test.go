@@ -0,0 +1,154 @@
+package astilog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astikit"
+)
+
+func TestLokiWriter(t *testing.T) {
+	// Mock now
+	oldNow := now
+	defer func() { now = oldNow }()
+	n := time.Unix(5, 6)
+	now = func() time.Time { return n }
+
+	// Create server
+	var ps []lokiPush
+	var count int
+	var header string
+	m := &sync.Mutex{}
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		m.Lock()
+		defer m.Unlock()
+
+		// First request is throttled
+		count++
+		if count == 1 {
+			rw.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		// Read body
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			rw.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		// Unmarshal
+		var p lokiPush
+		if err = json.Unmarshal(b, &p); err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		ps = append(ps, p)
+		header = r.Header.Get("X-Scope-OrgID")
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer s.Close()
+
+	// Create writer
+	w, err := newLokiWriter(Configuration{
+		AppName:         "app",
+		BatchInterval:   time.Hour,
+		HTTPHeaders:     map[string]string{"X-Scope-OrgID": "tenant"},
+		LokiLabels:      []string{"app_name", "level", "env.name"},
+		LokiURL:         s.URL,
+		TimestampFormat: "2006",
+	}, n)
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating writer failed: %w", err))
+	}
+	defer w.Close()
+
+	// Write
+	for _, e := range []entry{
+		{fs: map[string]interface{}{"env.name": "prod", "k": "v"}, l: astikit.LoggerLevelError, msg: "msg1", t: n},
+		{fs: map[string]interface{}{"app_name": "app", "env.name": "prod"}, l: astikit.LoggerLevelError, msg: "msg2", t: n},
+		{l: astikit.LoggerLevelInfo, msg: "msg3", t: n},
+	} {
+		if err = w.writeEntry(e, nil); err != nil {
+			t.Fatal(fmt.Errorf("writing failed: %w", err))
+		}
+	}
+
+	// Flush
+	if err = w.Flush(); err != nil {
+		t.Fatal(fmt.Errorf("flushing failed: %w", err))
+	}
+
+	// Assert
+	m.Lock()
+	defer m.Unlock()
+	if e, g := 2, count; e != g {
+		t.Errorf("expected %d, got %d", e, g)
+	}
+	if e, g := "tenant", header; e != g {
+		t.Errorf("expected %s, got %s", e, g)
+	}
+	ts := strconv.FormatInt(n.UnixNano(), 10)
+	if e, g := []lokiPush{{Streams: []*lokiStream{
+		{
+			Stream: map[string]string{"app_name": "app", "env_name": "prod", "level": "error"},
+			Values: [][2]string{
+				{ts, `{"k":"v","level":"error","msg":"msg1","time":"1970"}`},
+				{ts, `{"level":"error","msg":"msg2","time":"1970"}`},
+			},
+		},
+		{
+			Stream: map[string]string{"app_name": "app", "level": "info"},
+			Values: [][2]string{{ts, `{"level":"info","msg":"msg3","time":"1970"}`}},
+		},
+	}}}, ps; !reflect.DeepEqual(e, g) {
+		eb, _ := json.Marshal(e)
+		gb, _ := json.Marshal(g)
+		t.Errorf("expected %s, got %s", eb, gb)
+	}
+}
+
+func TestLokiWriterNotRetryable(t *testing.T) {
+	// Create server
+	var count int
+	m := &sync.Mutex{}
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		m.Lock()
+		defer m.Unlock()
+		count++
+		rw.WriteHeader(http.StatusBadRequest)
+	}))
+	defer s.Close()
+
+	// Create writer
+	w, err := newLokiWriter(Configuration{LokiURL: s.URL}, time.Now())
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating writer failed: %w", err))
+	}
+	defer w.Close()
+
+	// Write
+	if _, err = w.Write([]byte("msg\n")); err != nil {
+		t.Fatal(fmt.Errorf("writing failed: %w", err))
+	}
+
+	// Flush
+	if err = w.Flush(); err != nil {
+		t.Fatal(fmt.Errorf("flushing failed: %w", err))
+	}
+
+	// Assert
+	m.Lock()
+	defer m.Unlock()
+	if e, g := 1, count; e != g {
+		t.Errorf("expected %d, got %d", e, g)
+	}
+}
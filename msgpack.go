@@ -0,0 +1,380 @@
+package astilog
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// msgpackAppend appends the MessagePack encoding of v to b. Unknown types are encoded
+// as strings.
+func msgpackAppend(b []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xc0)
+	case bool:
+		if v {
+			return append(b, 0xc3)
+		}
+		return append(b, 0xc2)
+	case int:
+		return msgpackAppendInt(b, int64(v))
+	case int8:
+		return msgpackAppendInt(b, int64(v))
+	case int16:
+		return msgpackAppendInt(b, int64(v))
+	case int32:
+		return msgpackAppendInt(b, int64(v))
+	case int64:
+		return msgpackAppendInt(b, v)
+	case uint:
+		return msgpackAppendUint(b, uint64(v))
+	case uint8:
+		return msgpackAppendUint(b, uint64(v))
+	case uint16:
+		return msgpackAppendUint(b, uint64(v))
+	case uint32:
+		return msgpackAppendUint(b, uint64(v))
+	case uint64:
+		return msgpackAppendUint(b, v)
+	case float32:
+		b = append(b, 0xca)
+		return appendUint32(b, math.Float32bits(v))
+	case float64:
+		b = append(b, 0xcb)
+		return appendUint64(b, math.Float64bits(v))
+	case string:
+		return msgpackAppendString(b, v)
+	case []byte:
+		return msgpackAppendBinary(b, v)
+	case time.Time:
+		return msgpackAppendEventTime(b, v)
+	case []interface{}:
+		b = msgpackAppendArrayHeader(b, len(v))
+		for _, i := range v {
+			b = msgpackAppend(b, i)
+		}
+		return b
+	case map[string]interface{}:
+		// Sort keys so that the output is stable
+		ks := make([]string, 0, len(v))
+		for k := range v {
+			ks = append(ks, k)
+		}
+		sort.Strings(ks)
+
+		// Append
+		b = msgpackAppendMapHeader(b, len(v))
+		for _, k := range ks {
+			b = msgpackAppendString(b, k)
+			b = msgpackAppend(b, v[k])
+		}
+		return b
+	case error:
+		return msgpackAppendString(b, v.Error())
+	default:
+		return msgpackAppendString(b, fmt.Sprintf("%v", v))
+	}
+}
+
+func appendUint16(b []byte, i uint16) []byte {
+	return append(b, byte(i>>8), byte(i))
+}
+
+func appendUint32(b []byte, i uint32) []byte {
+	return append(b, byte(i>>24), byte(i>>16), byte(i>>8), byte(i))
+}
+
+func appendUint64(b []byte, i uint64) []byte {
+	return append(b, byte(i>>56), byte(i>>48), byte(i>>40), byte(i>>32), byte(i>>24), byte(i>>16), byte(i>>8), byte(i))
+}
+
+func msgpackAppendInt(b []byte, i int64) []byte {
+	switch {
+	case i >= 0:
+		return msgpackAppendUint(b, uint64(i))
+	case i >= -32:
+		return append(b, byte(i))
+	case i >= math.MinInt8:
+		return append(b, 0xd0, byte(i))
+	case i >= math.MinInt16:
+		return appendUint16(append(b, 0xd1), uint16(i))
+	case i >= math.MinInt32:
+		return appendUint32(append(b, 0xd2), uint32(i))
+	default:
+		return appendUint64(append(b, 0xd3), uint64(i))
+	}
+}
+
+func msgpackAppendUint(b []byte, i uint64) []byte {
+	switch {
+	case i <= 0x7f:
+		return append(b, byte(i))
+	case i <= math.MaxUint8:
+		return append(b, 0xcc, byte(i))
+	case i <= math.MaxUint16:
+		return appendUint16(append(b, 0xcd), uint16(i))
+	case i <= math.MaxUint32:
+		return appendUint32(append(b, 0xce), uint32(i))
+	default:
+		return appendUint64(append(b, 0xcf), i)
+	}
+}
+
+func msgpackAppendString(b []byte, s string) []byte {
+	switch l := len(s); {
+	case l <= 31:
+		b = append(b, 0xa0|byte(l))
+	case l <= math.MaxUint8:
+		b = append(b, 0xd9, byte(l))
+	case l <= math.MaxUint16:
+		b = appendUint16(append(b, 0xda), uint16(l))
+	default:
+		b = appendUint32(append(b, 0xdb), uint32(l))
+	}
+	return append(b, s...)
+}
+
+func msgpackAppendBinary(b []byte, i []byte) []byte {
+	switch l := len(i); {
+	case l <= math.MaxUint8:
+		b = append(b, 0xc4, byte(l))
+	case l <= math.MaxUint16:
+		b = appendUint16(append(b, 0xc5), uint16(l))
+	default:
+		b = appendUint32(append(b, 0xc6), uint32(l))
+	}
+	return append(b, i...)
+}
+
+func msgpackAppendArrayHeader(b []byte, l int) []byte {
+	switch {
+	case l <= 15:
+		return append(b, 0x90|byte(l))
+	case l <= math.MaxUint16:
+		return appendUint16(append(b, 0xdc), uint16(l))
+	default:
+		return appendUint32(append(b, 0xdd), uint32(l))
+	}
+}
+
+func msgpackAppendMapHeader(b []byte, l int) []byte {
+	switch {
+	case l <= 15:
+		return append(b, 0x80|byte(l))
+	case l <= math.MaxUint16:
+		return appendUint16(append(b, 0xde), uint16(l))
+	default:
+		return appendUint32(append(b, 0xdf), uint32(l))
+	}
+}
+
+// msgpackEventTimeType is the ext type used by fluentd for times with nanoseconds
+const msgpackEventTimeType = 0
+
+func msgpackAppendEventTime(b []byte, t time.Time) []byte {
+	b = append(b, 0xd7, msgpackEventTimeType)
+	b = appendUint32(b, uint32(t.Unix()))
+	return appendUint32(b, uint32(t.Nanosecond()))
+}
+
+// msgpackExt represents a MessagePack extension
+type msgpackExt struct {
+	data []byte
+	typ  int8
+}
+
+// msgpackDecode decodes the next MessagePack value. Maps are decoded as
+// map[string]interface{} and their keys are formatted if they're not strings.
+func msgpackDecode(r *bufio.Reader) (v interface{}, err error) {
+	// Read first byte
+	var c byte
+	if c, err = r.ReadByte(); err != nil {
+		return
+	}
+
+	// Switch on first byte
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return msgpackDecodeMap(r, int(c&0x0f))
+	case c&0xf0 == 0x90:
+		return msgpackDecodeArray(r, int(c&0x0f))
+	case c&0xe0 == 0xa0:
+		return msgpackDecodeString(r, int(c&0x1f))
+	}
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		var l int
+		if l, err = msgpackDecodeLength(r, c-0xc4); err != nil {
+			return
+		}
+		return msgpackDecodeBytes(r, l)
+	case 0xca:
+		var b []byte
+		if b, err = msgpackDecodeBytes(r, 4); err != nil {
+			return
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case 0xcb:
+		var b []byte
+		if b, err = msgpackDecodeBytes(r, 8); err != nil {
+			return
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		var b []byte
+		if b, err = msgpackDecodeBytes(r, 1<<(c-0xcc)); err != nil {
+			return
+		}
+		var i uint64
+		for _, c := range b {
+			i = i<<8 | uint64(c)
+		}
+		return i, nil
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		var b []byte
+		if b, err = msgpackDecodeBytes(r, 1<<(c-0xd0)); err != nil {
+			return
+		}
+		var i uint64
+		for _, c := range b {
+			i = i<<8 | uint64(c)
+		}
+		shift := uint(64 - 8*len(b))
+		return int64(i<<shift) >> shift, nil
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return msgpackDecodeExt(r, 1<<(c-0xd4))
+	case 0xc7, 0xc8, 0xc9:
+		var l int
+		if l, err = msgpackDecodeLength(r, c-0xc7); err != nil {
+			return
+		}
+		return msgpackDecodeExt(r, l)
+	case 0xd9, 0xda, 0xdb:
+		var l int
+		if l, err = msgpackDecodeLength(r, c-0xd9); err != nil {
+			return
+		}
+		return msgpackDecodeString(r, l)
+	case 0xdc, 0xdd:
+		var l int
+		if l, err = msgpackDecodeLength(r, c-0xdc+1); err != nil {
+			return
+		}
+		return msgpackDecodeArray(r, l)
+	case 0xde, 0xdf:
+		var l int
+		if l, err = msgpackDecodeLength(r, c-0xde+1); err != nil {
+			return
+		}
+		return msgpackDecodeMap(r, l)
+	}
+	return nil, fmt.Errorf("invalid msgpack byte %#x", c)
+}
+
+// msgpackDecodeLength decodes a length stored on 1, 2 or 4 bytes based on its
+// size index (0, 1 or 2)
+func msgpackDecodeLength(r *bufio.Reader, sizeIdx byte) (l int, err error) {
+	var b []byte
+	if b, err = msgpackDecodeBytes(r, 1<<sizeIdx); err != nil {
+		return
+	}
+	for _, c := range b {
+		l = l<<8 | int(c)
+	}
+	return
+}
+
+func msgpackDecodeBytes(r *bufio.Reader, l int) (b []byte, err error) {
+	b = make([]byte, l)
+	if _, err = io.ReadFull(r, b); err != nil {
+		return
+	}
+	return
+}
+
+func msgpackDecodeString(r *bufio.Reader, l int) (interface{}, error) {
+	b, err := msgpackDecodeBytes(r, l)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func msgpackDecodeExt(r *bufio.Reader, l int) (interface{}, error) {
+	t, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	b, err := msgpackDecodeBytes(r, l)
+	if err != nil {
+		return nil, err
+	}
+	return msgpackExt{
+		data: b,
+		typ:  int8(t),
+	}, nil
+}
+
+func msgpackDecodeArray(r *bufio.Reader, l int) (interface{}, error) {
+	vs := make([]interface{}, 0, l)
+	for i := 0; i < l; i++ {
+		v, err := msgpackDecode(r)
+		if err != nil {
+			return nil, err
+		}
+		vs = append(vs, v)
+	}
+	return vs, nil
+}
+
+func msgpackDecodeMap(r *bufio.Reader, l int) (interface{}, error) {
+	m := make(map[string]interface{}, l)
+	for i := 0; i < l; i++ {
+		// Decode key
+		k, err := msgpackDecode(r)
+		if err != nil {
+			return nil, err
+		}
+
+		// Decode value
+		v, err := msgpackDecode(r)
+		if err != nil {
+			return nil, err
+		}
+
+		// Add
+		switch k := k.(type) {
+		case string:
+			m[k] = v
+		default:
+			m[fmt.Sprintf("%v", k)] = v
+		}
+	}
+	return m, nil
+}
+
+var errMsgpackInvalidEventTime = errors.New("invalid event time")
+
+func msgpackEventTime(v interface{}) (time.Time, error) {
+	e, ok := v.(msgpackExt)
+	if !ok || e.typ != msgpackEventTimeType || len(e.data) != 8 {
+		return time.Time{}, errMsgpackInvalidEventTime
+	}
+	return time.Unix(int64(binary.BigEndian.Uint32(e.data[:4])), int64(binary.BigEndian.Uint32(e.data[4:]))), nil
+}
@@ -0,0 +1,89 @@
+package astilog
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMsgpack(t *testing.T) {
+	// Encode
+	ts := time.Unix(5, 6).UTC()
+	var b []byte
+	for _, v := range []interface{}{
+		nil,
+		true,
+		false,
+		1,
+		200,
+		70000,
+		uint64(math.MaxUint64),
+		-1,
+		-100,
+		-40000,
+		int64(math.MinInt64),
+		float32(1.5),
+		2.5,
+		"str",
+		string(make([]byte, 300)),
+		[]byte("bin"),
+		[]interface{}{1, "a"},
+		map[string]interface{}{"b": 1, "a": []interface{}{}},
+		fmt.Errorf("err"),
+		struct{ A int }{A: 1},
+		ts,
+	} {
+		b = msgpackAppend(b, v)
+	}
+
+	// Decode
+	r := bufio.NewReader(bytes.NewReader(b))
+	var vs []interface{}
+	for i := 0; i < 21; i++ {
+		v, err := msgpackDecode(r)
+		if err != nil {
+			t.Fatal(fmt.Errorf("decoding %d failed: %w", i, err))
+		}
+		vs = append(vs, v)
+	}
+	if _, err := msgpackDecode(r); err == nil {
+		t.Error("expected error, got nil")
+	}
+
+	// Assert
+	tv, err := msgpackEventTime(vs[20])
+	if err != nil {
+		t.Error(fmt.Errorf("expected no error, got %w", err))
+	}
+	if !tv.Equal(ts) {
+		t.Errorf("expected %s, got %s", ts, tv)
+	}
+	if e, g := []interface{}{
+		nil,
+		true,
+		false,
+		int64(1),
+		uint64(200),
+		uint64(70000),
+		uint64(math.MaxUint64),
+		int64(-1),
+		int64(-100),
+		int64(-40000),
+		int64(math.MinInt64),
+		1.5,
+		2.5,
+		"str",
+		string(make([]byte, 300)),
+		[]byte("bin"),
+		[]interface{}{int64(1), "a"},
+		map[string]interface{}{"b": int64(1), "a": []interface{}{}},
+		"err",
+		"{1}",
+	}, vs[:20]; !reflect.DeepEqual(e, g) {
+		t.Errorf("expected %+v, got %+v", e, g)
+	}
+}
@@ -0,0 +1,353 @@
+package astilog
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Net networks
+const (
+	NetNetworkTCP  = "tcp"
+	NetNetworkUDP  = "udp"
+	NetNetworkUnix = "unix"
+)
+
+// NetState represents the connection state of a network output
+type NetState string
+
+// Net states
+const (
+	NetStateConnected    NetState = "connected"
+	NetStateDisconnected NetState = "disconnected"
+)
+
+// NetStateHandler is called when the connection state of a network output changes. err
+// is the error that caused the disconnection, if any.
+type NetStateHandler func(address string, s NetState, err error)
+
+const (
+	netDefaultBufferSize = 1 << 20
+	netTimeout           = 5 * time.Second
+)
+
+// parseNetworkAddress splits addresses such as "tcp://host:port" or "unix:///path" into
+// a network and an address. If no scheme is provided, the default network is used.
+func parseNetworkAddress(i, defaultNetwork string) (network, address string, err error) {
+	// No scheme
+	p := strings.Index(i, "://")
+	if p == -1 {
+		network = defaultNetwork
+		address = i
+	} else {
+		network = i[:p]
+		address = i[p+3:]
+	}
+
+	// No address
+	if address == "" {
+		err = fmt.Errorf("invalid address %s", i)
+		return
+	}
+	return
+}
+
+// isNetOut returns whether the out is a network address
+func isNetOut(out string) bool {
+	return strings.Contains(out, "://")
+}
+
+// netWriter streams formatted entries to a socket. When the connection is lost, entries
+// are stored in a bounded buffer while it reconnects in the background.
+type netWriter struct {
+	dropped        uint64 // First to be 64-bit aligned for atomic operations
+	buf            []netItem
+	bufSize        int // Number of buffered bytes
+	closed         bool
+	closing        chan struct{}
+	conn           net.Conn
+	dial           func() (net.Conn, error)
+	m              *sync.Mutex // Locks buf, bufSize, closed, conn and reconnecting
+	maxBufSize     int
+	maxWriteLength int
+	out            string
+	reconnecting   bool
+	stateHandler   NetStateHandler
+	wg             *sync.WaitGroup
+}
+
+// netItem is an entry waiting to be written. cs are the counters of the entry, if any.
+type netItem struct {
+	b  []byte
+	cs *sinkCounters
+}
+
+func newNetWriter(c Configuration) (w *netWriter, err error) {
+	// Parse address
+	var network, address string
+	if network, address, err = parseNetworkAddress(c.Out, ""); err != nil {
+		return
+	}
+	switch network {
+	case NetNetworkTCP, NetNetworkUDP, NetNetworkUnix:
+	default:
+		err = fmt.Errorf("unknown network %s", network)
+		return
+	}
+
+	// Start
+	w = startNetWriter(c, c.Out, func() (net.Conn, error) { return net.DialTimeout(network, address, netTimeout) })
+	return
+}
+
+// startNetWriter creates a net writer connecting with the dial function. If the first
+// connection fails, it reconnects in the background.
+func startNetWriter(c Configuration, out string, dial func() (net.Conn, error)) (w *netWriter) {
+	// Create
+	w = &netWriter{
+		closing:        make(chan struct{}),
+		dial:           dial,
+		m:              &sync.Mutex{},
+		maxBufSize:     c.NetBufferSize,
+		maxWriteLength: c.MaxWriteLength,
+		out:            out,
+		stateHandler:   c.NetStateHandler,
+		wg:             &sync.WaitGroup{},
+	}
+
+	// Default buffer size
+	if w.maxBufSize <= 0 {
+		w.maxBufSize = netDefaultBufferSize
+	}
+
+	// Dial
+	conn, err := w.dial()
+	if err != nil {
+		// Reconnect in the background
+		w.m.Lock()
+		w.disconnected()
+		w.m.Unlock()
+		w.notify(NetStateDisconnected, fmt.Errorf("dialing %s failed: %w", w.out, err))
+		return
+	}
+	w.conn = conn
+	w.notify(NetStateConnected, nil)
+	return
+}
+
+func (w *netWriter) notify(s NetState, err error) {
+	if w.stateHandler != nil {
+		w.stateHandler(w.out, s, err)
+	}
+}
+
+// disconnected starts reconnecting in the background. It assumes the lock is held.
+func (w *netWriter) disconnected() {
+	// Close connection
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+
+	// Already reconnecting
+	if w.reconnecting || w.closed {
+		return
+	}
+	w.reconnecting = true
+
+	// Reconnect
+	w.wg.Add(1)
+	go w.reconnect()
+}
+
+func (w *netWriter) reconnect() {
+	defer w.wg.Done()
+	for attempt := 0; ; attempt++ {
+		// Wait
+		select {
+		case <-time.After(batchBackoff(attempt)):
+		case <-w.closing:
+			return
+		}
+
+		// Dial
+		conn, err := w.dial()
+		if err != nil {
+			continue
+		}
+
+		// Lock
+		w.m.Lock()
+
+		// Writer is closed
+		if w.closed {
+			w.m.Unlock()
+			conn.Close()
+			return
+		}
+
+		// Update connection
+		w.conn = conn
+		w.reconnecting = false
+
+		// Flush buffer
+		err = w.flushBuffer()
+		w.m.Unlock()
+
+		// Notify
+		w.notify(NetStateConnected, nil)
+		if err != nil {
+			w.notify(NetStateDisconnected, err)
+		}
+		return
+	}
+}
+
+// flushBuffer writes buffered entries. It assumes the lock is held.
+func (w *netWriter) flushBuffer() error {
+	for len(w.buf) > 0 {
+		if err := w.write(w.buf[0]); err != nil {
+			w.disconnected()
+			return err
+		}
+		w.bufSize -= len(w.buf[0].b)
+		w.buf = w.buf[1:]
+	}
+	w.buf = nil
+	return nil
+}
+
+// write writes the item and counts it. It assumes the lock is held.
+func (w *netWriter) write(i netItem) (err error) {
+	if err = w.conn.SetWriteDeadline(time.Now().Add(netTimeout)); err != nil {
+		return fmt.Errorf("setting write deadline failed: %w", err)
+	}
+	if err = writeBytes(w.conn, i.b, w.maxWriteLength); err != nil {
+		return fmt.Errorf("writing to %s failed: %w", w.out, err)
+	}
+	i.cs.written(len(i.b), nil)
+	return
+}
+
+// buffer stores the item until the connection is back. Oldest items are dropped when
+// the buffer is full. It assumes the lock is held.
+func (w *netWriter) buffer(i netItem) {
+	// Item is too big
+	if len(i.b) > w.maxBufSize {
+		w.drop(i)
+		return
+	}
+
+	// Drop oldest items
+	for w.bufSize+len(i.b) > w.maxBufSize && len(w.buf) > 0 {
+		w.bufSize -= len(w.buf[0].b)
+		w.drop(w.buf[0])
+		w.buf = w.buf[1:]
+	}
+
+	// Add
+	w.buf = append(w.buf, i)
+	w.bufSize += len(i.b)
+}
+
+func (w *netWriter) drop(i netItem) {
+	atomic.AddUint64(&w.dropped, 1)
+	i.cs.failed(1)
+}
+
+// Write implements the io.Writer interface
+func (w *netWriter) Write(p []byte) (n int, err error) {
+	if err = w.add(p, nil); err != nil {
+		return
+	}
+	n = len(p)
+	return
+}
+
+func (w *netWriter) writeEntry(e entry, b []byte) error {
+	return w.add(b, e.cs)
+}
+
+func (w *netWriter) add(p []byte, cs *sinkCounters) (err error) {
+	// Caller may reuse the slice
+	i := netItem{
+		b:  make([]byte, len(p)),
+		cs: cs,
+	}
+	copy(i.b, p)
+
+	// Lock
+	w.m.Lock()
+
+	// Writer is closed
+	if w.closed {
+		w.m.Unlock()
+		err = errors.New("net writer is closed")
+		return
+	}
+
+	// Not connected
+	if w.conn == nil {
+		w.buffer(i)
+		w.m.Unlock()
+		return
+	}
+
+	// Write
+	var errWrite error
+	if errWrite = w.flushBuffer(); errWrite == nil {
+		if errWrite = w.write(i); errWrite != nil {
+			w.disconnected()
+		}
+	}
+	if errWrite != nil {
+		w.buffer(i)
+	}
+	w.m.Unlock()
+
+	// Notify
+	if errWrite != nil {
+		w.notify(NetStateDisconnected, errWrite)
+	}
+	return
+}
+
+// Close implements the io.Closer interface
+func (w *netWriter) Close() (err error) {
+	// Lock
+	w.m.Lock()
+	if w.closed {
+		w.m.Unlock()
+		return
+	}
+	w.closed = true
+	close(w.closing)
+
+	// Close connection
+	if w.conn != nil {
+		err = w.conn.Close()
+		w.conn = nil
+	}
+
+	// Buffered items are dropped
+	for _, i := range w.buf {
+		w.drop(i)
+	}
+	w.buf = nil
+	w.bufSize = 0
+	w.m.Unlock()
+
+	// Wait for reconnection to stop
+	w.wg.Wait()
+	return
+}
+
+// Dropped returns the number of entries dropped because the buffer was full or because
+// the writer was closed before they could be written
+func (w *netWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
@@ -0,0 +1,294 @@
+package astilog
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseNetworkAddress(t *testing.T) {
+	for _, v := range []struct {
+		address string
+		err     bool
+		i       string
+		network string
+	}{
+		{address: "127.0.0.1:24224", i: "127.0.0.1:24224", network: "tcp"},
+		{address: "127.0.0.1:24224", i: "tcp://127.0.0.1:24224", network: "tcp"},
+		{address: "/var/run/fluent.sock", i: "unix:///var/run/fluent.sock", network: "unix"},
+		{err: true, i: "unix://"},
+	} {
+		network, address, err := parseNetworkAddress(v.i, "tcp")
+		if v.err {
+			if err == nil {
+				t.Error("expected error, got nil")
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+		if v.network != network {
+			t.Errorf("expected %s, got %s", v.network, network)
+		}
+		if v.address != address {
+			t.Errorf("expected %s, got %s", v.address, address)
+		}
+	}
+}
+
+type testNetStates struct {
+	m  *sync.Mutex
+	ss []NetState
+}
+
+func (s *testNetStates) handler(address string, st NetState, err error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.ss = append(s.ss, st)
+}
+
+func (s *testNetStates) get() []NetState {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return append([]NetState{}, s.ss...)
+}
+
+func TestNetWriterTCP(t *testing.T) {
+	// Listen
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(fmt.Errorf("listening failed: %w", err))
+	}
+	addr := ln.Addr().String()
+
+	// Read lines
+	lines := make(chan string, 10)
+	accept := func(ln net.Listener) {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					lines <- l
+				}
+			}()
+		}
+	}
+	go accept(ln)
+
+	// Create writer
+	ss := &testNetStates{m: &sync.Mutex{}}
+	w, err := newNetWriter(Configuration{
+		NetStateHandler: ss.handler,
+		Out:             "tcp://" + addr,
+	})
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating writer failed: %w", err))
+	}
+	defer w.Close()
+
+	// Write
+	if _, err = w.Write([]byte("msg1\n")); err != nil {
+		t.Fatal(fmt.Errorf("writing failed: %w", err))
+	}
+	if e, g := "msg1\n", <-lines; e != g {
+		t.Errorf("expected %s, got %s", e, g)
+	}
+
+	// Simulate a disconnection
+	ln.Close()
+	w.m.Lock()
+	w.disconnected()
+	w.m.Unlock()
+
+	// Write while disconnected
+	for _, msg := range []string{"msg2\n", "msg3\n"} {
+		if _, err = w.Write([]byte(msg)); err != nil {
+			t.Fatal(fmt.Errorf("writing failed: %w", err))
+		}
+	}
+
+	// Listen again
+	if ln, err = net.Listen("tcp", addr); err != nil {
+		t.Fatal(fmt.Errorf("listening failed: %w", err))
+	}
+	defer ln.Close()
+	go accept(ln)
+
+	// Buffered entries should be sent once reconnected
+	for _, e := range []string{"msg2\n", "msg3\n"} {
+		select {
+		case g := <-lines:
+			if e != g {
+				t.Errorf("expected %s, got %s", e, g)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out")
+		}
+	}
+
+	// Assert states
+	if e, g := []NetState{NetStateConnected, NetStateConnected}, ss.get(); fmt.Sprint(e) != fmt.Sprint(g) {
+		t.Errorf("expected %v, got %v", e, g)
+	}
+}
+
+func TestNetWriterUDP(t *testing.T) {
+	// Listen
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(fmt.Errorf("listening failed: %w", err))
+	}
+	defer conn.Close()
+
+	// Create writer
+	w, err := newNetWriter(Configuration{Out: "udp://" + conn.LocalAddr().String()})
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating writer failed: %w", err))
+	}
+	defer w.Close()
+
+	// Write
+	if _, err = w.Write([]byte("msg\n")); err != nil {
+		t.Fatal(fmt.Errorf("writing failed: %w", err))
+	}
+
+	// Read
+	b := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(time.Second)) //nolint: errcheck
+	n, _, err := conn.ReadFrom(b)
+	if err != nil {
+		t.Fatal(fmt.Errorf("reading failed: %w", err))
+	}
+	if e, g := "msg\n", string(b[:n]); e != g {
+		t.Errorf("expected %s, got %s", e, g)
+	}
+}
+
+func TestNetWriterUnixBuffer(t *testing.T) {
+	// Create dir
+	dir, err := ioutil.TempDir("", "astilog_")
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating temp dir failed: %w", err))
+	}
+	defer os.RemoveAll(dir)
+	p := filepath.Join(dir, "sock")
+
+	// Create writer while nothing is listening
+	ss := &testNetStates{m: &sync.Mutex{}}
+	w, err := newNetWriter(Configuration{
+		NetBufferSize:   10,
+		NetStateHandler: ss.handler,
+		Out:             "unix://" + p,
+	})
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating writer failed: %w", err))
+	}
+	defer w.Close()
+
+	// Write more than the buffer size
+	cs := &sinkCounters{}
+	for _, msg := range []string{"msg1\n", "msg2\n", "msg3\n"} {
+		if err = w.writeEntry(entry{cs: cs}, []byte(msg)); err != nil {
+			t.Fatal(fmt.Errorf("writing failed: %w", err))
+		}
+	}
+	if e, g := uint64(1), w.Dropped(); e != g {
+		t.Errorf("expected %d, got %d", e, g)
+	}
+
+	// Buffered entries are not counted as written
+	if e, g := (sinkCounters{failures: 1}), cs.load(); e != g {
+		t.Errorf("expected %+v, got %+v", e, g)
+	}
+	if e, g := []NetState{NetStateDisconnected}, ss.get(); fmt.Sprint(e) != fmt.Sprint(g) {
+		t.Errorf("expected %v, got %v", e, g)
+	}
+
+	// Listen
+	ln, err := net.Listen("unix", p)
+	if err != nil {
+		t.Skip(fmt.Errorf("listening failed: %w", err))
+	}
+	defer ln.Close()
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(fmt.Errorf("accepting failed: %w", err))
+	}
+	defer conn.Close()
+
+	// Oldest entry should have been dropped
+	r := bufio.NewReader(conn)
+	for _, e := range []string{"msg2\n", "msg3\n"} {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second)) //nolint: errcheck
+		g, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(fmt.Errorf("reading failed: %w", err))
+		}
+		if e != g {
+			t.Errorf("expected %s, got %s", e, g)
+		}
+	}
+
+	// Entries are counted once written
+	w.Close()
+	if e, g := (sinkCounters{bytes: 10, failures: 1, writes: 2}), cs.load(); e != g {
+		t.Errorf("expected %+v, got %+v", e, g)
+	}
+}
+
+func TestNetWriterClose(t *testing.T) {
+	// Get an address nothing is listening to
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(fmt.Errorf("listening failed: %w", err))
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	// Create writer
+	w, err := newNetWriter(Configuration{Out: "tcp://" + addr})
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating writer failed: %w", err))
+	}
+
+	// Write
+	cs := &sinkCounters{}
+	for i := 0; i < 3; i++ {
+		if err = w.writeEntry(entry{cs: cs}, []byte("msg\n")); err != nil {
+			t.Fatal(fmt.Errorf("writing failed: %w", err))
+		}
+	}
+
+	// Buffered entries are dropped when closing
+	if err = w.Close(); err != nil {
+		t.Fatal(fmt.Errorf("closing failed: %w", err))
+	}
+	if e, g := uint64(3), w.Dropped(); e != g {
+		t.Errorf("expected %d, got %d", e, g)
+	}
+	if e, g := (sinkCounters{failures: 3}), cs.load(); e != g {
+		t.Errorf("expected %+v, got %+v", e, g)
+	}
+}
+
+func TestNetWriterInvalidNetwork(t *testing.T) {
+	if _, err := newNetWriter(Configuration{Out: "invalid://address"}); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
@@ -0,0 +1,266 @@
+package astilog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/asticode/go-astikit"
+)
+
+const otlpScopeName = "github.com/asticode/go-astilog"
+
+// OTLP severity numbers
+const (
+	otlpSeverityNumberDebug = 5
+	otlpSeverityNumberInfo  = 9
+	otlpSeverityNumberWarn  = 13
+	otlpSeverityNumberError = 17
+	otlpSeverityNumberFatal = 21
+)
+
+func otlpSeverityNumber(l astikit.LoggerLevel) int {
+	switch l {
+	case astikit.LoggerLevelDebug:
+		return otlpSeverityNumberDebug
+	case astikit.LoggerLevelWarn:
+		return otlpSeverityNumberWarn
+	case astikit.LoggerLevelError:
+		return otlpSeverityNumberError
+	case astikit.LoggerLevelFatal:
+		return otlpSeverityNumberFatal
+	default:
+		return otlpSeverityNumberInfo
+	}
+}
+
+// otlpWriter exports entries in batches as OTLP log records using the OTLP/HTTP JSON
+// encoding
+type otlpWriter struct {
+	*batchWriter
+	c        Configuration
+	hc       *httpClient
+	resource otlpResource
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	IntValue    string   `json:"intValue,omitempty"`
+	StringValue *string  `json:"stringValue,omitempty"`
+}
+
+type otlpLogRecord struct {
+	Attributes           []otlpKeyValue `json:"attributes,omitempty"`
+	Body                 otlpAnyValue   `json:"body"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+	SeverityNumber       int            `json:"severityNumber"`
+	SeverityText         string         `json:"severityText"`
+	SpanID               string         `json:"spanId,omitempty"`
+	TimeUnixNano         string         `json:"timeUnixNano"`
+	TraceID              string         `json:"traceId,omitempty"`
+}
+
+type otlpExportLogsServiceRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpScopeLogs struct {
+	LogRecords []json.RawMessage `json:"logRecords"`
+	Scope      otlpScope         `json:"scope"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+func newOTLPWriter(c Configuration) (w *otlpWriter, err error) {
+	// Check url
+	if c.OTLPURL == "" {
+		err = errors.New("otlp url is empty")
+		return
+	}
+
+	// Create
+	w = &otlpWriter{
+		c:  c,
+		hc: newHTTPClient(c),
+	}
+
+	// Create resource
+	as := make(map[string]interface{}, len(c.OTLPResource)+1)
+	if c.AppName != "" {
+		as["service.name"] = c.AppName
+	}
+	for k, v := range c.OTLPResource {
+		as[k] = v
+	}
+	w.resource.Attributes = otlpAttributes(as)
+
+	// Create batch writer
+	w.batchWriter = newBatchWriter(c, w.send, nil)
+	return
+}
+
+func otlpAttributes(fs map[string]interface{}) (kvs []otlpKeyValue) {
+	// Sort keys so that the output is stable
+	var ks []string
+	for k := range fs {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+
+	// Loop through keys
+	kvs = make([]otlpKeyValue, 0, len(ks))
+	for _, k := range ks {
+		kvs = append(kvs, otlpKeyValue{
+			Key:   k,
+			Value: otlpValue(fs[k]),
+		})
+	}
+	return
+}
+
+func otlpValue(i interface{}) (v otlpAnyValue) {
+	switch i := i.(type) {
+	case bool:
+		v.BoolValue = &i
+	case float32:
+		f := float64(i)
+		v.DoubleValue = &f
+	case float64:
+		v.DoubleValue = &i
+	case int:
+		v.IntValue = strconv.FormatInt(int64(i), 10)
+	case int8:
+		v.IntValue = strconv.FormatInt(int64(i), 10)
+	case int16:
+		v.IntValue = strconv.FormatInt(int64(i), 10)
+	case int32:
+		v.IntValue = strconv.FormatInt(int64(i), 10)
+	case int64:
+		v.IntValue = strconv.FormatInt(i, 10)
+	case uint:
+		v = otlpUintValue(uint64(i))
+	case uint8:
+		v.IntValue = strconv.FormatUint(uint64(i), 10)
+	case uint16:
+		v.IntValue = strconv.FormatUint(uint64(i), 10)
+	case uint32:
+		v.IntValue = strconv.FormatUint(uint64(i), 10)
+	case uint64:
+		v = otlpUintValue(i)
+	case string:
+		v.StringValue = &i
+	default:
+		s := fmt.Sprintf("%v", i)
+		v.StringValue = &s
+	}
+	return
+}
+
+// otlpUintValue returns a string value when the value overflows int64
+func otlpUintValue(i uint64) (v otlpAnyValue) {
+	s := strconv.FormatUint(i, 10)
+	if i > math.MaxInt64 {
+		v.StringValue = &s
+	} else {
+		v.IntValue = s
+	}
+	return
+}
+
+// Write implements the io.Writer interface
+func (w *otlpWriter) Write(p []byte) (int, error) {
+	if err := w.writeEntry(entry{
+		l:   astikit.LoggerLevelInfo,
+		msg: string(bytes.TrimRight(p, "\n")),
+		t:   now(),
+	}, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// buildsPayload implements the payloadBuilder interface
+func (w *otlpWriter) buildsPayload() bool {
+	return true
+}
+
+func (w *otlpWriter) writeEntry(e entry, _ []byte) error {
+	// Create record
+	r := otlpLogRecord{
+		Attributes:           otlpAttributes(e.fs),
+		Body:                 otlpValue(e.msg),
+		ObservedTimeUnixNano: strconv.FormatInt(now().UnixNano(), 10),
+		SeverityNumber:       otlpSeverityNumber(e.l),
+		SeverityText:         strings.ToUpper(e.l.String()),
+		SpanID:               e.spanID,
+		TimeUnixNano:         strconv.FormatInt(e.t.UnixNano(), 10),
+		TraceID:              e.traceID,
+	}
+
+	// Marshal
+	b, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("marshaling failed: %w", err)
+	}
+
+	// Add
+	return w.add(batchItem{
+		b: b,
+		e: e,
+	})
+}
+
+func (w *otlpWriter) send(is []batchItem) (retry, dropped []batchItem, err error) {
+	// Create request
+	rs := make([]json.RawMessage, 0, len(is))
+	for _, i := range is {
+		rs = append(rs, i.b)
+	}
+	r := otlpExportLogsServiceRequest{ResourceLogs: []otlpResourceLogs{{
+		Resource: w.resource,
+		ScopeLogs: []otlpScopeLogs{{
+			LogRecords: rs,
+			Scope:      otlpScope{Name: otlpScopeName},
+		}},
+	}}}
+
+	// Marshal
+	var b []byte
+	if b, err = json.Marshal(r); err != nil {
+		err = fmt.Errorf("marshaling failed: %w", err)
+		return
+	}
+
+	// Send
+	var retryable bool
+	if _, retryable, err = w.hc.do(http.MethodPost, w.c.OTLPURL, "application/json", b, nil); err != nil {
+		if retryable {
+			retry = is
+		}
+		return
+	}
+	return
+}
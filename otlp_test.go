@@ -0,0 +1,114 @@
+package astilog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astikit"
+)
+
+func TestOTLPSeverityNumber(t *testing.T) {
+	for _, v := range []struct {
+		e int
+		l astikit.LoggerLevel
+	}{
+		{e: 5, l: astikit.LoggerLevelDebug},
+		{e: 9, l: astikit.LoggerLevelInfo},
+		{e: 13, l: astikit.LoggerLevelWarn},
+		{e: 17, l: astikit.LoggerLevelError},
+		{e: 21, l: astikit.LoggerLevelFatal},
+	} {
+		if g := otlpSeverityNumber(v.l); v.e != g {
+			t.Errorf("expected %d, got %d", v.e, g)
+		}
+	}
+}
+
+func TestOTLPValue(t *testing.T) {
+	for _, v := range []struct {
+		e string
+		i interface{}
+	}{
+		{e: `{"intValue":"1"}`, i: 1},
+		{e: `{"intValue":"2"}`, i: uint(2)},
+		{e: `{"intValue":"3"}`, i: uint8(3)},
+		{e: `{"intValue":"9223372036854775807"}`, i: uint64(math.MaxInt64)},
+		{e: `{"stringValue":"18446744073709551615"}`, i: uint64(math.MaxUint64)},
+		{e: `{"doubleValue":1.5}`, i: 1.5},
+		{e: `{"boolValue":true}`, i: true},
+		{e: `{"stringValue":"v"}`, i: "v"},
+	} {
+		b, err := json.Marshal(otlpValue(v.i))
+		if err != nil {
+			t.Fatal(fmt.Errorf("marshaling failed: %w", err))
+		}
+		if g := string(b); v.e != g {
+			t.Errorf("expected %s, got %s", v.e, g)
+		}
+	}
+}
+
+func TestOTLPWriter(t *testing.T) {
+	// Mock now
+	oldNow := now
+	defer func() { now = oldNow }()
+	now = func() time.Time { return time.Unix(5, 6) }
+
+	// Create server
+	var bodies []string
+	m := &sync.Mutex{}
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		m.Lock()
+		defer m.Unlock()
+		b, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			rw.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		bodies = append(bodies, string(b))
+		rw.Write([]byte("{}")) //nolint: errcheck
+	}))
+	defer s.Close()
+
+	// Create logger
+	l := New(Configuration{
+		AppName:       "app",
+		BatchInterval: time.Hour,
+		OTLPResource:  map[string]string{"deployment.environment": "prod"},
+		OTLPURL:       s.URL,
+		Out:           OutOTLP,
+	})
+	defer l.Close()
+
+	// Write
+	ctx := ContextWithTrace(ContextWithField(context.Background(), "k", 1), "5b8efff798038103d269b633813fc60c", "eee19b7ec3c1b174")
+	l.ErrorC(ctx, "msg1")
+	l.Debug("msg2")
+
+	// Flush
+	if err := l.Flush(); err != nil {
+		t.Fatal(fmt.Errorf("flushing failed: %w", err))
+	}
+
+	// Assert
+	m.Lock()
+	defer m.Unlock()
+	if e, g := 1, len(bodies); e != g {
+		t.Fatalf("expected %d, got %d", e, g)
+	}
+	var r otlpExportLogsServiceRequest
+	if err := json.Unmarshal([]byte(bodies[0]), &r); err != nil {
+		t.Fatal(fmt.Errorf("unmarshaling failed: %w", err))
+	}
+	if e, g := `{"resourceLogs":[{"resource":{"attributes":[{"key":"deployment.environment","value":{"stringValue":"prod"}},{"key":"service.name","value":{"stringValue":"app"}}]},"scopeLogs":[{"logRecords":[{"attributes":[{"key":"app_name","value":{"stringValue":"app"}},{"key":"k","value":{"intValue":"1"}}],"body":{"stringValue":"msg1"},"observedTimeUnixNano":"5000000006","severityNumber":17,"severityText":"ERROR","spanId":"eee19b7ec3c1b174","timeUnixNano":"5000000006","traceId":"5b8efff798038103d269b633813fc60c"},{"attributes":[{"key":"app_name","value":{"stringValue":"app"}}],"body":{"stringValue":"msg2"},"observedTimeUnixNano":"5000000006","severityNumber":5,"severityText":"DEBUG","timeUnixNano":"5000000006"}],"scope":{"name":"github.com/asticode/go-astilog"}}]}]}`, bodies[0]; e != g {
+		t.Errorf("expected %s, got %s", e, g)
+	}
+}
@@ -0,0 +1,264 @@
+package astilog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/asticode/go-astikit"
+)
+
+const ringBufferDefaultMaxEntries = 1000
+
+// RingBuffer keeps the last entries in memory. Use it as the RingBuffer option of a sink
+// and query it with Entries or through HTTP since it implements the http.Handler
+// interface.
+type RingBuffer struct {
+	es         []RingBufferEntry
+	m          *sync.RWMutex // Locks es and size
+	maxBytes   int
+	maxEntries int
+	size       int
+}
+
+// RingBufferOptions represents ring buffer options. When both options are empty, the
+// last 1000 entries are kept.
+type RingBufferOptions struct {
+	// Max number of bytes used by messages and fields
+	MaxBytes int
+	// Max number of entries
+	MaxEntries int
+}
+
+// RingBufferEntry represents an entry stored in a ring buffer. Fields must not be
+// modified.
+type RingBufferEntry struct {
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+	Level   astikit.LoggerLevel    `json:"level"`
+	Message string                 `json:"msg"`
+	Time    time.Time              `json:"time"`
+}
+
+// RingBufferQuery represents a ring buffer query. Empty options are ignored.
+type RingBufferQuery struct {
+	// Fields whose values formatted with %v must match
+	Fields map[string]string
+	// Entries must have been logged at or after this time
+	From time.Time
+	// Min level of entries
+	Level astikit.LoggerLevel
+	// Max number of entries, the most recent ones are returned
+	Limit int
+	// Entries must have been logged before this time
+	To time.Time
+}
+
+// NewRingBuffer creates a new ring buffer
+func NewRingBuffer(o RingBufferOptions) *RingBuffer {
+	b := &RingBuffer{
+		m:          &sync.RWMutex{},
+		maxBytes:   o.MaxBytes,
+		maxEntries: o.MaxEntries,
+	}
+	if b.maxBytes <= 0 && b.maxEntries <= 0 {
+		b.maxEntries = ringBufferDefaultMaxEntries
+	}
+	return b
+}
+
+func ringBufferEntrySize(e RingBufferEntry) (n int) {
+	n = len(e.Message)
+	for k, v := range e.Fields {
+		n += len(k) + len(fmt.Sprintf("%v", v))
+	}
+	return
+}
+
+// add adds the entry and returns its size
+func (b *RingBuffer) add(e RingBufferEntry) (size int) {
+	// Get size
+	size = ringBufferEntrySize(e)
+
+	// Lock
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	// Add
+	b.es = append(b.es, e)
+	b.size += size
+
+	// Remove oldest entries
+	var n int
+	for n < len(b.es)-1 && ((b.maxEntries > 0 && len(b.es)-n > b.maxEntries) || (b.maxBytes > 0 && b.size > b.maxBytes)) {
+		b.size -= ringBufferEntrySize(b.es[n])
+		n++
+	}
+	if n > 0 {
+		// Entries are copied so that the underlying array doesn't keep growing
+		b.es = append(b.es[:0:0], b.es[n:]...)
+	}
+	return
+}
+
+// Entries returns the entries matching the query from the oldest to the most recent
+func (b *RingBuffer) Entries(q RingBufferQuery) (es []RingBufferEntry) {
+	// Lock
+	b.m.RLock()
+	defer b.m.RUnlock()
+
+	// Loop through entries starting with the most recent
+	for idx := len(b.es) - 1; idx >= 0; idx-- {
+		// Limit is reached
+		if q.Limit > 0 && len(es) >= q.Limit {
+			break
+		}
+
+		// Entry doesn't match
+		e := b.es[idx]
+		if !q.match(e) {
+			continue
+		}
+		es = append(es, e)
+	}
+
+	// Reverse
+	for i, j := 0, len(es)-1; i < j; i, j = i+1, j-1 {
+		es[i], es[j] = es[j], es[i]
+	}
+	return
+}
+
+func (q RingBufferQuery) match(e RingBufferEntry) bool {
+	// Level
+	if e.Level < q.Level {
+		return false
+	}
+
+	// Time range
+	if (!q.From.IsZero() && e.Time.Before(q.From)) || (!q.To.IsZero() && !e.Time.Before(q.To)) {
+		return false
+	}
+
+	// Fields
+	for k, v := range q.Fields {
+		fv, ok := e.Fields[k]
+		if !ok || fmt.Sprintf("%v", fv) != v {
+			return false
+		}
+	}
+	return true
+}
+
+// ServeHTTP implements the http.Handler interface and returns entries as a JSON array.
+// Entries can be filtered with the "level", "from", "to" (RFC3339) and "limit" query
+// parameters, and with "field.<key>=<value>" query parameters.
+func (b *RingBuffer) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	// Check method
+	if r.Method != http.MethodGet {
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Parse query
+	q, err := newRingBufferQueryFromHTTP(r)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Get entries
+	es := b.Entries(q)
+	if es == nil {
+		es = []RingBufferEntry{}
+	}
+
+	// Write
+	rw.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(rw).Encode(es); err != nil {
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+func newRingBufferQueryFromHTTP(r *http.Request) (q RingBufferQuery, err error) {
+	// Loop through query parameters
+	for k, vs := range r.URL.Query() {
+		// Get value
+		if len(vs) == 0 {
+			continue
+		}
+		v := vs[0]
+
+		// Switch on key
+		switch {
+		case k == "from":
+			if q.From, err = time.Parse(time.RFC3339, v); err != nil {
+				err = fmt.Errorf("parsing from failed: %w", err)
+				return
+			}
+		case k == "level":
+			q.Level = astikit.LoggerLevelFromString(v)
+		case k == "limit":
+			if q.Limit, err = strconv.Atoi(v); err != nil {
+				err = fmt.Errorf("parsing limit failed: %w", err)
+				return
+			}
+		case k == "to":
+			if q.To, err = time.Parse(time.RFC3339, v); err != nil {
+				err = fmt.Errorf("parsing to failed: %w", err)
+				return
+			}
+		case strings.HasPrefix(k, "field."):
+			if q.Fields == nil {
+				q.Fields = make(map[string]string)
+			}
+			q.Fields[strings.TrimPrefix(k, "field.")] = v
+		}
+	}
+	return
+}
+
+// ringWriter writes entries to a ring buffer
+type ringWriter struct {
+	b *RingBuffer
+}
+
+func newRingWriter(b *RingBuffer) *ringWriter {
+	return &ringWriter{b: b}
+}
+
+// Close implements the io.Closer interface
+func (w *ringWriter) Close() error {
+	return nil
+}
+
+// Write implements the io.Writer interface
+func (w *ringWriter) Write(p []byte) (int, error) {
+	w.b.add(RingBufferEntry{
+		Level:   astikit.LoggerLevelInfo,
+		Message: string(bytes.TrimRight(p, "\n")),
+		Time:    now(),
+	})
+	return len(p), nil
+}
+
+// buildsPayload implements the payloadBuilder interface
+func (w *ringWriter) buildsPayload() bool {
+	return true
+}
+
+func (w *ringWriter) writeEntry(e entry, _ []byte) error {
+	n := w.b.add(RingBufferEntry{
+		Fields:  e.fs,
+		Level:   e.l,
+		Message: e.msg,
+		Time:    e.t,
+	})
+	e.cs.written(n, nil)
+	return nil
+}
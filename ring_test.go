@@ -0,0 +1,137 @@
+package astilog
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astikit"
+)
+
+func ringBufferMessages(es []RingBufferEntry) (ms []string) {
+	for _, e := range es {
+		ms = append(ms, e.Message)
+	}
+	return
+}
+
+func TestRingBuffer(t *testing.T) {
+	// Mock now
+	oldNow := now
+	defer func() { now = oldNow }()
+	n := time.Unix(0, 0).UTC()
+	now = func() time.Time { return n }
+
+	// Create logger
+	b := NewRingBuffer(RingBufferOptions{MaxEntries: 3})
+	l := New(Configuration{Level: astikit.LoggerLevelDebug, Sinks: []Configuration{{RingBuffer: b}}})
+	defer l.Close()
+
+	// Log
+	l.Info("1")
+	n = n.Add(time.Second)
+	l.WithField("k", 1)
+	l.Warn("2")
+	n = n.Add(time.Second)
+	l.Debug("3")
+	n = n.Add(time.Second)
+	l.Error("4")
+
+	// Max entries
+	if e, g := []string{"2", "3", "4"}, ringBufferMessages(b.Entries(RingBufferQuery{})); !reflect.DeepEqual(e, g) {
+		t.Errorf("expected %+v, got %+v", e, g)
+	}
+	if e, g := (RingBufferEntry{
+		Fields:  map[string]interface{}{"k": 1},
+		Level:   astikit.LoggerLevelWarn,
+		Message: "2",
+		Time:    time.Unix(1, 0).UTC(),
+	}), b.Entries(RingBufferQuery{})[0]; !reflect.DeepEqual(e, g) {
+		t.Errorf("expected %+v, got %+v", e, g)
+	}
+
+	// Queries
+	for _, v := range []struct {
+		e []string
+		q RingBufferQuery
+	}{
+		{e: []string{"2", "4"}, q: RingBufferQuery{Level: astikit.LoggerLevelWarn}},
+		{e: []string{"3"}, q: RingBufferQuery{From: time.Unix(2, 0), To: time.Unix(3, 0)}},
+		{e: []string{"2", "3", "4"}, q: RingBufferQuery{Fields: map[string]string{"k": "1"}}},
+		{q: RingBufferQuery{Fields: map[string]string{"k": "2"}}},
+		{e: []string{"3", "4"}, q: RingBufferQuery{Limit: 2}},
+	} {
+		if g := ringBufferMessages(b.Entries(v.q)); !reflect.DeepEqual(v.e, g) {
+			t.Errorf("expected %+v, got %+v", v.e, g)
+		}
+	}
+
+	// Max bytes
+	b = NewRingBuffer(RingBufferOptions{MaxBytes: 5})
+	w := newRingWriter(b)
+	for _, m := range []string{"12", "34", "56\n"} {
+		if _, err := w.Write([]byte(m)); err != nil {
+			t.Fatal(fmt.Errorf("writing failed: %w", err))
+		}
+	}
+	if e, g := []string{"34", "56"}, ringBufferMessages(b.Entries(RingBufferQuery{})); !reflect.DeepEqual(e, g) {
+		t.Errorf("expected %+v, got %+v", e, g)
+	}
+}
+
+func TestRingBufferServeHTTP(t *testing.T) {
+	// Create ring buffer
+	b := NewRingBuffer(RingBufferOptions{})
+	n := time.Unix(0, 0).UTC()
+	b.add(RingBufferEntry{Fields: map[string]interface{}{"k": "a"}, Level: astikit.LoggerLevelInfo, Message: "1", Time: n})
+	b.add(RingBufferEntry{Fields: map[string]interface{}{"k": "b"}, Level: astikit.LoggerLevelError, Message: "2", Time: n.Add(time.Second)})
+
+	// Invalid method
+	rw := httptest.NewRecorder()
+	b.ServeHTTP(rw, httptest.NewRequest(http.MethodPost, "/", nil))
+	if e, g := http.StatusMethodNotAllowed, rw.Code; e != g {
+		t.Errorf("expected %d, got %d", e, g)
+	}
+
+	// Invalid query
+	rw = httptest.NewRecorder()
+	b.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/?from=invalid", nil))
+	if e, g := http.StatusBadRequest, rw.Code; e != g {
+		t.Errorf("expected %d, got %d", e, g)
+	}
+
+	// Queries
+	for _, v := range []struct {
+		e []string
+		q string
+	}{
+		{e: []string{"1", "2"}, q: ""},
+		{e: []string{"2"}, q: "level=error"},
+		{e: []string{"1"}, q: "field.k=a"},
+		{e: []string{"2"}, q: "from=1970-01-01T00:00:01Z"},
+		{e: []string{"1"}, q: "to=1970-01-01T00:00:01Z"},
+		{e: []string{"2"}, q: "limit=1"},
+		{e: []string{}, q: "field.k=c"},
+	} {
+		rw = httptest.NewRecorder()
+		b.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/?"+v.q, nil))
+		if e, g := http.StatusOK, rw.Code; e != g {
+			t.Errorf("expected %d, got %d", e, g)
+		}
+		var es []RingBufferEntry
+		if err := json.Unmarshal(rw.Body.Bytes(), &es); err != nil {
+			t.Fatal(fmt.Errorf("unmarshaling failed: %w", err))
+		}
+		g := []string{}
+		for _, e := range es {
+			g = append(g, e.Message)
+		}
+		if !reflect.DeepEqual(v.e, g) {
+			t.Errorf("expected %+v, got %+v for %s", v.e, g, v.q)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package astilog
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+func (l *Logger) handleReopenSignal() (stop func()) {
+	// Listen to signals
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+
+	// Handle signals
+	done := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-ch:
+				if err := l.Reopen(); err != nil {
+					handleError(l.c.ErrorHandler, fmt.Errorf("astilog: reopening failed: %w", err))
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	// Create stop function
+	var o sync.Once
+	return func() {
+		o.Do(func() {
+			signal.Stop(ch)
+			close(done)
+			wg.Wait()
+		})
+	}
+}
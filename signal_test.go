@@ -0,0 +1,62 @@
+// +build !windows
+
+package astilog
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReopenSignal(t *testing.T) {
+	// Create temp dir
+	d, err := ioutil.TempDir("", "astilog_")
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating temp dir failed: %w", err))
+	}
+
+	// Make sure to delete directory
+	defer os.RemoveAll(d)
+
+	// Create logger
+	p := filepath.Join(d, "f.log")
+	l := New(Configuration{
+		Filename:       p,
+		Format:         FormatMinimalist,
+		ReopenOnSIGHUP: true,
+	})
+	defer l.Close()
+
+	// Move file
+	l.Info("1")
+	if err = os.Rename(p, p+".old"); err != nil {
+		t.Fatal(fmt.Errorf("renaming failed: %w", err))
+	}
+
+	// Send signal
+	if err = syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatal(fmt.Errorf("sending signal failed: %w", err))
+	}
+
+	// Wait for the file to be reopened
+	for i := 0; i < 100; i++ {
+		if _, err = os.Stat(p); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Assert
+	l.Info("2")
+	b, err := ioutil.ReadFile(p)
+	if err != nil {
+		t.Fatal(fmt.Errorf("reading %s failed: %w", p, err))
+	}
+	if e := "2\n"; e != string(b) {
+		t.Errorf("expected %s, got %s", e, b)
+	}
+}
@@ -0,0 +1,328 @@
+package astilog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/asticode/go-astikit"
+)
+
+// sink represents an output with its own writer, level and formatter
+type sink struct {
+	c  Configuration
+	cs *sinkCounters
+	f  Formatter
+	l  astikit.LoggerLevel // Level
+	w  io.WriteCloser
+}
+
+func newSink(c Configuration, createdAt time.Time) (s *sink) {
+	// Create
+	s = &sink{
+		c:  c,
+		cs: &sinkCounters{},
+	}
+
+	// Set writer
+	s.setWriter(c, createdAt)
+
+	// Write asynchronously
+	if c.Async {
+		s.w = newAsyncWriter(c, s.w)
+	}
+
+	// Set level
+	s.setLevel(c)
+
+	// Set formatter
+	s.setFormatter(c, createdAt)
+	return
+}
+
+// sinkConfiguration returns the sink configuration where options that are not specific
+// to a sink are inherited from the logger configuration
+func sinkConfiguration(lc, sc Configuration) Configuration {
+	if sc.AppName == "" {
+		sc.AppName = lc.AppName
+	}
+	if sc.ErrorHandler == nil {
+		sc.ErrorHandler = lc.ErrorHandler
+	}
+	if sc.MaxWriteLength == 0 {
+		sc.MaxWriteLength = lc.MaxWriteLength
+	}
+	if sc.TimestampFormat == "" {
+		sc.TimestampFormat = lc.TimestampFormat
+	}
+	return sc
+}
+
+func (s *sink) setWriter(c Configuration, createdAt time.Time) {
+	// Fallbacks
+	if len(c.Fallbacks) > 0 {
+		s.w = newFallbackWriter(c, createdAt)
+		return
+	}
+
+	// Create
+	w, err := newWriter(c, createdAt)
+	if err != nil {
+		// Default is stdout
+		w = astikit.NopCloser(os.Stdout)
+		handleError(c.ErrorHandler, fmt.Errorf("astilog: %w", err))
+	}
+	s.w = w
+}
+
+// outputName describes the configuration's output
+func outputName(c Configuration) string {
+	if c.RingBuffer != nil {
+		return "ring_buffer"
+	} else if c.Filename != "" {
+		return c.Filename
+	} else if c.Out != "" {
+		return c.Out
+	}
+	return OutStdout
+}
+
+// newWriter creates the writer of the configuration's output
+func newWriter(c Configuration, createdAt time.Time) (w io.WriteCloser, err error) {
+	// Ring buffer
+	if c.RingBuffer != nil {
+		return newRingWriter(c.RingBuffer), nil
+	}
+
+	// File
+	if c.Filename != "" {
+		f, err := newFileWriter(c)
+		if err != nil {
+			return nil, fmt.Errorf("creating %s failed: %w", c.Filename, err)
+		}
+		return f, nil
+	}
+
+	// Network
+	if isNetOut(c.Out) {
+		nw, err := newNetWriter(c)
+		if err != nil {
+			return nil, fmt.Errorf("creating %s failed: %w", c.Out, err)
+		}
+		return nw, nil
+	}
+
+	// Switch on out
+	switch c.Out {
+	case OutElasticsearch:
+		ew, err := newElasticsearchWriter(c, createdAt)
+		if err != nil {
+			return nil, fmt.Errorf("creating elasticsearch failed: %w", err)
+		}
+		return ew, nil
+	case OutFluentd:
+		fw, err := newFluentdWriter(c)
+		if err != nil {
+			return nil, fmt.Errorf("creating fluentd failed: %w", err)
+		}
+		return fw, nil
+	case OutGELF:
+		gw, err := newGELFWriter(c)
+		if err != nil {
+			return nil, fmt.Errorf("creating gelf failed: %w", err)
+		}
+		return gw, nil
+	case OutJournald:
+		jw, err := newJournaldWriter(c)
+		if err != nil {
+			return nil, fmt.Errorf("creating journald failed: %w", err)
+		}
+		return jw, nil
+	case OutLoki:
+		lw, err := newLokiWriter(c, createdAt)
+		if err != nil {
+			return nil, fmt.Errorf("creating loki failed: %w", err)
+		}
+		return lw, nil
+	case OutOTLP:
+		ow, err := newOTLPWriter(c)
+		if err != nil {
+			return nil, fmt.Errorf("creating otlp failed: %w", err)
+		}
+		return ow, nil
+	case OutStderr:
+		return astikit.NopCloser(os.Stderr), nil
+	case OutSyslog:
+		if c.SyslogAddress != "" {
+			sw, err := newRemoteSyslogWriter(c)
+			if err != nil {
+				return nil, fmt.Errorf("creating syslog failed: %w", err)
+			}
+			return sw, nil
+		}
+		if w, err = newSyslogWriter(c); err != nil {
+			return nil, fmt.Errorf("creating syslog failed: %w", err)
+		}
+		return
+	case OutWebhook:
+		ww, err := newWebhookWriter(c, createdAt)
+		if err != nil {
+			return nil, fmt.Errorf("creating webhook failed: %w", err)
+		}
+		return ww, nil
+	default:
+		return astikit.NopCloser(os.Stdout), nil
+	}
+}
+
+func (s *sink) setLevel(c Configuration) {
+	s.l = c.Level
+}
+
+func (s *sink) setFormatter(c Configuration, createdAt time.Time) {
+	// Some outs have a default format
+	format := c.Format
+	if format == "" && c.Out == OutGELF {
+		format = FormatGELF
+	}
+
+	// Default is text
+	if format == "" {
+		format = FormatText
+	}
+
+	// Create formatter
+	var err error
+	if s.f, err = newFormatter(format, c, createdAt); err != nil {
+		s.f = newTextFormatter(c, createdAt)
+		handleError(c.ErrorHandler, fmt.Errorf("astilog: creating formatter failed: %w", err))
+	}
+}
+
+// entry represents a log entry
+type entry struct {
+	cs      *sinkCounters // Counters of the sink writing the entry
+	fs      map[string]interface{}
+	l       astikit.LoggerLevel // Level
+	msg     string
+	source  string
+	spanID  string
+	t       time.Time
+	traceID string
+}
+
+// export returns the entry as it's provided to formatters
+func (e entry) export() Entry {
+	return Entry{
+		Fields:  e.fs,
+		Level:   e.l,
+		Message: e.msg,
+		Source:  e.source,
+		Time:    e.t,
+	}
+}
+
+// entryWriter is implemented by writers that need the entry in addition to the
+// formatted bytes. They count entries with the entry's counters once they have written
+// them or given up on them, whereas errors they return are counted by the caller.
+type entryWriter interface {
+	writeEntry(e entry, b []byte) error
+}
+
+// payloadBuilder is implemented by entry writers that build their own payload out of
+// the entry, in which case entries are not formatted before being written to them
+type payloadBuilder interface {
+	buildsPayload() bool
+}
+
+func buildsPayload(w io.Writer) bool {
+	pb, ok := w.(payloadBuilder)
+	return ok && pb.buildsPayload()
+}
+
+func (s *sink) write(e entry) (err error) {
+	// Entry is counted by the writer delivering it
+	e.cs = s.cs
+
+	// Format message
+	var b []byte
+	if !buildsPayload(s.w) {
+		if b, err = s.f.Format(e.export()); err != nil {
+			s.cs.written(0, err)
+			return fmt.Errorf("formatting failed: %w", err)
+		}
+	}
+
+	// Write
+	if err = writeEntry(s.w, e, b, s.c.MaxWriteLength); err != nil {
+		s.cs.written(0, err)
+		return fmt.Errorf("writing failed: %w", err)
+	}
+	return nil
+}
+
+func writeEntry(w io.Writer, e entry, b []byte, maxWriteLength int) (err error) {
+	// Writer handles entries
+	if ew, ok := w.(entryWriter); ok {
+		return ew.writeEntry(e, b)
+	}
+
+	// Write bytes
+	if err = writeBytes(w, b, maxWriteLength); err != nil {
+		return
+	}
+
+	// Count
+	e.cs.written(len(b), nil)
+	return
+}
+
+func writeBytes(w io.Writer, m []byte, maxWriteLength int) error {
+	// Write
+	if maxWriteLength > 0 && len(m) > maxWriteLength {
+		// Loop
+		var c int
+		for {
+			// Get boundaries
+			from := c * maxWriteLength
+			to := (c + 1) * maxWriteLength
+
+			// We're done
+			if from > len(m)-1 {
+				break
+			}
+
+			// We've reached the end of the message
+			if to > len(m) {
+				to = len(m)
+			}
+
+			// Since append modifies the input slice, we need to create a new one when
+			// appending a new line
+			wm := m[from:to]
+			if to != len(m) {
+				wm = make([]byte, to-from)
+				copy(wm, m[from:to])
+				if !bytes.HasSuffix(wm, newLine) {
+					wm = append(wm, newLine...)
+				}
+			}
+
+			// Write
+			if _, err := w.Write(wm); err != nil {
+				return err
+			}
+
+			// Increment
+			c++
+		}
+	} else {
+		// Write
+		if _, err := w.Write(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
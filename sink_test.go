@@ -0,0 +1,149 @@
+package astilog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astikit"
+)
+
+func TestSetWriter(t *testing.T) {
+	// Create temp dir
+	d, err := ioutil.TempDir("", "astilog_")
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating temp dir failed: %w", err))
+	}
+
+	// Make sure to delete directory
+	defer os.RemoveAll(d)
+
+	// Create sink
+	s := newSink(FlagConfig(), time.Unix(0, 0))
+	defer s.w.Close()
+
+	// Default to stdout
+	if !reflect.DeepEqual(s.w, astikit.NopCloser(os.Stdout)) {
+		t.Error("expected false, got true")
+	}
+
+	// File
+	f := filepath.Join(d, "f1.log")
+	s.setWriter(Configuration{Filename: f}, time.Now())
+	switch tp := s.w.(type) {
+	case *fileWriter:
+	default:
+		t.Errorf("expected *fileWriter, got %T", tp)
+	}
+	s.w.Write([]byte("test")) //nolint: errcheck
+	b, err := ioutil.ReadFile(f)
+	if err != nil {
+		t.Fatal(fmt.Errorf("reading %s failed: %w", f, err))
+	}
+	if e := []byte("test"); !bytes.Equal(e, b) {
+		t.Errorf("expected %s, got %s", e, b)
+	}
+
+	// File not working defaults to stdout
+	s.setWriter(Configuration{Filename: filepath.Join("testdata/invalidpath")}, time.Now())
+	if !reflect.DeepEqual(s.w, astikit.NopCloser(os.Stdout)) {
+		t.Error("expected false, got true")
+	}
+
+	// Syslog
+	s.setWriter(Configuration{Out: OutSyslog}, time.Now())
+	switch tp := s.w.(type) {
+	case *syslogWriter:
+	default:
+		t.Errorf("expected *syslogWriter, got %T", tp)
+	}
+
+	// Bypass newSyslogWriter
+	old := newSyslogWriter
+	newSyslogWriter = func(c Configuration) (io.WriteCloser, error) { return nil, errors.New("dummy") }
+	defer func() { newSyslogWriter = old }()
+
+	// syslog not working defaults to stdout
+	s.setWriter(Configuration{Out: OutSyslog}, time.Now())
+	if !reflect.DeepEqual(s.w, astikit.NopCloser(os.Stdout)) {
+		t.Error("expected false, got true")
+	}
+
+	// Stderr
+	s.setWriter(Configuration{Out: OutStderr}, time.Now())
+	if !reflect.DeepEqual(s.w, astikit.NopCloser(os.Stderr)) {
+		t.Error("expected false, got true")
+	}
+}
+
+func TestSetFormatter(t *testing.T) {
+	s := newSink(FlagConfig(), time.Unix(0, 0))
+	defer s.w.Close()
+	switch tp := s.f.(type) {
+	case *textFormatter:
+	default:
+		t.Errorf("expected *textFormatter, got %T", tp)
+	}
+	s.setFormatter(Configuration{Format: FormatJSON}, time.Unix(0, 0))
+	switch tp := s.f.(type) {
+	case *jsonFormatter:
+	default:
+		t.Errorf("expected *jsonFormatter, got %T", tp)
+	}
+}
+
+func TestSinks(t *testing.T) {
+	// Create logger
+	l := New(Configuration{
+		AppName: "app",
+		Level:   astikit.LoggerLevelInfo,
+		Sinks: []Configuration{
+			{Format: FormatJSON, MessageKey: "message"},
+			{Format: FormatText, Level: astikit.LoggerLevelError},
+		},
+	})
+	defer l.Close()
+	b1 := &bytes.Buffer{}
+	l.ss[0].w = astikit.NopCloser(b1)
+	b2 := &bytes.Buffer{}
+	l.ss[1].w = astikit.NopCloser(b2)
+
+	// Write
+	l.Debug("debug")
+	l.Info("info")
+	l.Error("error")
+
+	// Assert
+	if e, g := `{"app_name":"app","level":"info","message":"info","time":0}
+{"app_name":"app","level":"error","message":"error","time":0}
+`, b1.String(); e != g {
+		t.Errorf("expected %s, got %s", e, g)
+	}
+	if e, g := "ERROR[0000]error  app_name=app\n", b2.String(); e != g {
+		t.Errorf("expected %s, got %s", e, g)
+	}
+}
+
+type mockedCloser struct {
+	io.Writer
+	err error
+}
+
+func (c mockedCloser) Close() error { return c.err }
+
+func TestSinksClose(t *testing.T) {
+	l := New(Configuration{Sinks: []Configuration{{}, {}, {}}})
+	l.ss[0].w = mockedCloser{Writer: ioutil.Discard, err: errors.New("1")}
+	l.ss[1].w = mockedCloser{Writer: ioutil.Discard}
+	l.ss[2].w = mockedCloser{Writer: ioutil.Discard, err: errors.New("3")}
+	if err := l.Close(); err == nil || err.Error() != "1 && 3" {
+		t.Errorf("expected 1 && 3, got %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package astilog
+
+import (
+	"log"
+	"sync/atomic"
+)
+
+// ErrorHandler handles errors happening inside the logger such as outputs that can't be
+// opened or entries that can't be formatted, written or sent. When no error handler is
+// set, errors are logged with the standard log package.
+type ErrorHandler func(err error)
+
+func handleError(h ErrorHandler, err error) {
+	if h != nil {
+		h(err)
+		return
+	}
+	log.Println(err)
+}
+
+// Stats represents the logger stats
+type Stats struct {
+	// Sum of the sinks stats
+	Bytes    uint64
+	Dropped  uint64
+	Failures uint64
+	Writes   uint64
+	// Stats of each sink
+	Sinks []SinkStats
+}
+
+// SinkStats represents the stats of a sink
+type SinkStats struct {
+	// Number of bytes successfully written or sent
+	Bytes uint64
+	// Number of entries dropped because a buffer or a queue was full or because they
+	// couldn't be sent
+	Dropped uint64
+	// Number of entries that couldn't be formatted, written or sent
+	Failures uint64
+	// Sink output
+	Output string
+	// Number of entries successfully written or sent
+	Writes uint64
+}
+
+// sinkCounters counts writes of a sink. Entries are counted by the writer delivering
+// them, once they have been written or sent, or once it has given up on them.
+type sinkCounters struct {
+	bytes    uint64 // First to be 64-bit aligned for atomic operations
+	failures uint64
+	writes   uint64
+}
+
+// written counts a write of n bytes, or a failure if err is not nil. Counters are nil
+// for entries that are not written by a sink.
+func (c *sinkCounters) written(n int, err error) {
+	if err != nil {
+		c.failed(1)
+		return
+	}
+	c.add(1, n)
+}
+
+func (c *sinkCounters) add(writes, bytes int) {
+	if c == nil {
+		return
+	}
+	atomic.AddUint64(&c.bytes, uint64(bytes))
+	atomic.AddUint64(&c.writes, uint64(writes))
+}
+
+func (c *sinkCounters) failed(n int) {
+	if c == nil {
+		return
+	}
+	atomic.AddUint64(&c.failures, uint64(n))
+}
+
+// Stats returns the logger stats
+func (l *Logger) Stats() (s Stats) {
+	for _, sk := range l.ss {
+		// Get sink stats
+		ss := SinkStats{
+			Bytes:    atomic.LoadUint64(&sk.cs.bytes),
+			Failures: atomic.LoadUint64(&sk.cs.failures),
+			Output:   outputName(sk.c),
+			Writes:   atomic.LoadUint64(&sk.cs.writes),
+		}
+		if d, ok := sk.w.(dropper); ok {
+			ss.Dropped = d.Dropped()
+		}
+
+		// Add
+		s.Bytes += ss.Bytes
+		s.Dropped += ss.Dropped
+		s.Failures += ss.Failures
+		s.Writes += ss.Writes
+		s.Sinks = append(s.Sinks, ss)
+	}
+	return
+}
@@ -0,0 +1,160 @@
+package astilog
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astikit"
+)
+
+func TestStats(t *testing.T) {
+	// Create logger
+	var errs []error
+	m := &sync.Mutex{}
+	l := New(Configuration{
+		ErrorHandler: func(err error) {
+			m.Lock()
+			defer m.Unlock()
+			errs = append(errs, err)
+		},
+		Sinks: []Configuration{
+			{Format: FormatJSON},
+			{},
+			{Async: true},
+		},
+	})
+	defer l.Close()
+	buf := &bytes.Buffer{}
+	l.ss[0].w = astikit.NopCloser(buf)
+	l.ss[1].w = astikit.NopCloser(failingWriter{})
+	aw := l.ss[2].w.(*asyncWriter)
+	aw.w = astikit.NopCloser(failingWriter{})
+
+	// Log
+	l.Info("test")
+	l.WithField("k", make(chan int))
+	l.Info("test")
+	if err := l.Sync(); err != nil {
+		t.Fatal(fmt.Errorf("syncing failed: %w", err))
+	}
+
+	// Errors have been handled
+	if e, g := 5, len(errs); e != g {
+		t.Errorf("expected %d, got %d", e, g)
+	}
+
+	// Stats
+	s := l.Stats()
+	if e, g := 3, len(s.Sinks); e != g {
+		t.Fatalf("expected %d, got %d", e, g)
+	}
+	for idx, v := range []SinkStats{
+		{Bytes: uint64(buf.Len()), Failures: 1, Output: OutStdout, Writes: 1},
+		{Failures: 2, Output: OutStdout},
+		{Failures: 2, Output: OutStdout},
+	} {
+		if e, g := v, s.Sinks[idx]; e != g {
+			t.Errorf("expected %+v, got %+v for sink #%d", e, g, idx)
+		}
+	}
+	if e, g := (Stats{Bytes: uint64(buf.Len()), Failures: 5, Sinks: s.Sinks, Writes: 1}), s; fmt.Sprintf("%+v", e) != fmt.Sprintf("%+v", g) {
+		t.Errorf("expected %+v, got %+v", e, g)
+	}
+}
+
+type countingFormatter struct{ n *uint64 }
+
+func (f countingFormatter) Format(e Entry) ([]byte, error) {
+	atomic.AddUint64(f.n, 1)
+	return []byte(e.Message + "\n"), nil
+}
+
+func TestStatsDelivery(t *testing.T) {
+	// Create server
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/reject" {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer s.Close()
+
+	// Register format
+	var formats uint64
+	RegisterFormat("counting", func(c Configuration, createdAt time.Time) (Formatter, error) {
+		return countingFormatter{n: &formats}, nil
+	})
+	defer func() {
+		formatterFactoriesMutex.Lock()
+		delete(formatterFactories, "counting")
+		formatterFactoriesMutex.Unlock()
+	}()
+
+	// Create logger
+	l := New(Configuration{
+		BatchInterval: time.Hour,
+		ErrorHandler:  func(err error) {},
+		Sinks: []Configuration{
+			{LokiURL: s.URL + "/reject", Out: OutLoki},
+			{LokiURL: s.URL + "/accept", Out: OutLoki},
+			{Format: "counting", RingBuffer: NewRingBuffer(RingBufferOptions{})},
+			{Async: true, Format: "counting", RingBuffer: NewRingBuffer(RingBufferOptions{})},
+			{Format: "counting"},
+		},
+	})
+	defer l.Close()
+	l.ss[4].w = astikit.NopCloser(ioutil.Discard)
+
+	// Log
+	l.Info("msg1")
+	l.Info("msg2")
+	if err := l.Sync(); err != nil {
+		t.Fatal(fmt.Errorf("syncing failed: %w", err))
+	}
+
+	// Only writers that don't build their own payload need formatted entries
+	if e, g := uint64(2), atomic.LoadUint64(&formats); e != g {
+		t.Errorf("expected %d, got %d", e, g)
+	}
+
+	// Stats
+	st := l.Stats()
+	if e, g := 5, len(st.Sinks); e != g {
+		t.Fatalf("expected %d, got %d", e, g)
+	}
+	if e, g := (SinkStats{Dropped: 2, Failures: 2, Output: OutLoki}), st.Sinks[0]; e != g {
+		t.Errorf("expected %+v, got %+v", e, g)
+	}
+	if e, g := uint64(2), st.Sinks[1].Writes; e != g {
+		t.Errorf("expected %d, got %d", e, g)
+	}
+	if st.Sinks[1].Bytes == 0 {
+		t.Error("expected bytes to be counted")
+	}
+	size := uint64(ringBufferEntrySize(RingBufferEntry{Message: "msg1"}) + ringBufferEntrySize(RingBufferEntry{Message: "msg2"}))
+	for idx := 2; idx < 4; idx++ {
+		if e, g := (SinkStats{Bytes: size, Output: "ring_buffer", Writes: 2}), st.Sinks[idx]; e != g {
+			t.Errorf("expected %+v, got %+v for sink #%d", e, g, idx)
+		}
+	}
+	if e, g := (SinkStats{Bytes: 10, Output: OutStdout, Writes: 2}), st.Sinks[4]; e != g {
+		t.Errorf("expected %+v, got %+v", e, g)
+	}
+}
+
+// load returns a copy of the counters that is safe to compare
+func (c *sinkCounters) load() sinkCounters {
+	return sinkCounters{
+		bytes:    atomic.LoadUint64(&c.bytes),
+		failures: atomic.LoadUint64(&c.failures),
+		writes:   atomic.LoadUint64(&c.writes),
+	}
+}
@@ -5,8 +5,84 @@ package astilog
 import (
 	"io"
 	"log/syslog"
+
+	"github.com/asticode/go-astikit"
 )
 
+var syslogNew = syslog.New
+
 var newSyslogWriter = func(c Configuration) (io.WriteCloser, error) {
-	return syslog.New(syslog.LOG_INFO|syslog.LOG_USER, c.AppName)
+	// Get facility
+	f, err := syslogFacility(c.SyslogFacility)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get tag
+	tag := c.SyslogTag
+	if tag == "" {
+		tag = c.AppName
+	}
+
+	// Create
+	w, err := syslogNew(syslog.Priority(f<<3)|syslog.LOG_INFO, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &syslogWriter{
+		maxWriteLength: c.MaxWriteLength,
+		w:              w,
+	}, nil
+}
+
+// syslogWriter uses the syslog severity matching the entry level
+type syslogWriter struct {
+	maxWriteLength int
+	w              *syslog.Writer
+}
+
+// Close implements the io.Closer interface
+func (w *syslogWriter) Close() error {
+	return w.w.Close()
+}
+
+// Write implements the io.Writer interface
+func (w *syslogWriter) Write(p []byte) (int, error) {
+	return w.w.Write(p)
+}
+
+func (w *syslogWriter) writeEntry(e entry, b []byte) error {
+	// Get level function
+	var fn func(m string) error
+	switch e.l {
+	case astikit.LoggerLevelDebug:
+		fn = w.w.Debug
+	case astikit.LoggerLevelWarn:
+		fn = w.w.Warning
+	case astikit.LoggerLevelError:
+		fn = w.w.Err
+	case astikit.LoggerLevelFatal:
+		fn = w.w.Crit
+	default:
+		fn = w.w.Info
+	}
+
+	// Write
+	if err := writeBytes(syslogLevelWriter(fn), b, w.maxWriteLength); err != nil {
+		return err
+	}
+
+	// Count
+	e.cs.written(len(b), nil)
+	return nil
+}
+
+type syslogLevelWriter func(m string) error
+
+// Write implements the io.Writer interface
+func (fn syslogLevelWriter) Write(p []byte) (int, error) {
+	if err := fn(string(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
 }
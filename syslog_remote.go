@@ -0,0 +1,251 @@
+package astilog
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/asticode/go-astikit"
+)
+
+// Syslog networks
+const (
+	SyslogNetworkTCP = "tcp"
+	SyslogNetworkTLS = "tls"
+	SyslogNetworkUDP = "udp"
+)
+
+// Syslog severities as defined in RFC 5424
+const (
+	syslogSeverityCritical = 2
+	syslogSeverityError    = 3
+	syslogSeverityWarning  = 4
+	syslogSeverityInfo     = 6
+	syslogSeverityDebug    = 7
+)
+
+func syslogSeverity(l astikit.LoggerLevel) int {
+	switch l {
+	case astikit.LoggerLevelDebug:
+		return syslogSeverityDebug
+	case astikit.LoggerLevelWarn:
+		return syslogSeverityWarning
+	case astikit.LoggerLevelError:
+		return syslogSeverityError
+	case astikit.LoggerLevelFatal:
+		return syslogSeverityCritical
+	default:
+		return syslogSeverityInfo
+	}
+}
+
+var syslogFacilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// syslogFacility returns the facility code of its name. Default is "user".
+func syslogFacility(name string) (int, error) {
+	if name == "" {
+		return syslogFacilities["user"], nil
+	}
+	f, ok := syslogFacilities[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown syslog facility %s", name)
+	}
+	return f, nil
+}
+
+// syslogStructuredDataID is the SD-ID fields are added to. 32473 is the private
+// enterprise number reserved for documentation.
+const syslogStructuredDataID = "astilog@32473"
+
+const syslogTimestampFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// remoteSyslogWriter sends RFC 5424 messages to a remote syslog collector. Messages
+// are octet-counted when sent over TCP or TLS. Like network outputs, messages are
+// buffered while it reconnects in the background.
+type remoteSyslogWriter struct {
+	c        Configuration
+	facility int
+	hostname string
+	network  string
+	nw       *netWriter
+}
+
+func newRemoteSyslogWriter(c Configuration) (w *remoteSyslogWriter, err error) {
+	// Create
+	w = &remoteSyslogWriter{
+		c:       c,
+		network: c.SyslogNetwork,
+	}
+
+	// Get facility
+	if w.facility, err = syslogFacility(c.SyslogFacility); err != nil {
+		return
+	}
+
+	// Get network
+	if w.network == "" {
+		w.network = SyslogNetworkUDP
+	}
+	switch w.network {
+	case SyslogNetworkTCP, SyslogNetworkTLS, SyslogNetworkUDP:
+	default:
+		err = fmt.Errorf("unknown syslog network %s", w.network)
+		return
+	}
+
+	// Get hostname
+	if w.hostname, err = os.Hostname(); err != nil || w.hostname == "" {
+		w.hostname = "-"
+		err = nil
+	}
+
+	// Start net writer. Messages must not be split.
+	nc := c
+	nc.MaxWriteLength = 0
+	w.nw = startNetWriter(nc, c.SyslogAddress, w.dial)
+	return
+}
+
+func (w *remoteSyslogWriter) dial() (net.Conn, error) {
+	if w.network == SyslogNetworkTLS {
+		return tls.DialWithDialer(&net.Dialer{Timeout: netTimeout}, "tcp", w.c.SyslogAddress, w.c.SyslogTLSConfig)
+	}
+	return net.DialTimeout(w.network, w.c.SyslogAddress, netTimeout)
+}
+
+// Close implements the io.Closer interface
+func (w *remoteSyslogWriter) Close() error {
+	return w.nw.Close()
+}
+
+// Dropped implements the dropper interface
+func (w *remoteSyslogWriter) Dropped() uint64 {
+	return w.nw.Dropped()
+}
+
+// Write implements the io.Writer interface
+func (w *remoteSyslogWriter) Write(p []byte) (int, error) {
+	if err := w.writeEntry(entry{
+		l:   astikit.LoggerLevelInfo,
+		msg: string(bytes.TrimRight(p, "\n")),
+		t:   now(),
+	}, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// buildsPayload implements the payloadBuilder interface
+func (w *remoteSyslogWriter) buildsPayload() bool {
+	return true
+}
+
+func (w *remoteSyslogWriter) writeEntry(e entry, _ []byte) error {
+	// Build message
+	m := w.message(e)
+
+	// Add octet counting framing
+	if w.network == SyslogNetworkTCP || w.network == SyslogNetworkTLS {
+		m = append([]byte(strconv.Itoa(len(m))+" "), m...)
+	}
+
+	// Write
+	return w.nw.writeEntry(e, m)
+}
+
+// message builds an RFC 5424 message:
+// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+func (w *remoteSyslogWriter) message(e entry) []byte {
+	// Header
+	b := &bytes.Buffer{}
+	b.WriteString("<" + strconv.Itoa(w.facility*8+syslogSeverity(e.l)) + ">1 ")
+	b.WriteString(e.t.Format(syslogTimestampFormat) + " ")
+	b.WriteString(syslogHeaderField(w.hostname, 255) + " ")
+	b.WriteString(syslogHeaderField(w.c.AppName, 48) + " ")
+	b.WriteString(strconv.Itoa(os.Getpid()) + " - ")
+
+	// Structured data
+	if len(e.fs) > 0 {
+		// Sort keys
+		var ks []string
+		for k := range e.fs {
+			ks = append(ks, k)
+		}
+		sort.Strings(ks)
+
+		// Add params
+		b.WriteString("[" + syslogStructuredDataID)
+		for _, k := range ks {
+			b.WriteString(" " + syslogParamName(k) + "=\"" + syslogParamValue(fmt.Sprintf("%v", e.fs[k])) + "\"")
+		}
+		b.WriteString("]")
+	} else {
+		b.WriteString("-")
+	}
+
+	// Message
+	if e.msg != "" {
+		b.WriteString(" " + e.msg)
+	}
+	return b.Bytes()
+}
+
+// syslogHeaderField replaces invalid characters and makes sure the field is not empty
+func syslogHeaderField(i string, max int) string {
+	o := []byte(i)
+	for idx, c := range o {
+		if c < 33 || c > 126 {
+			o[idx] = '_'
+		}
+	}
+	if len(o) == 0 {
+		return "-"
+	} else if len(o) > max {
+		o = o[:max]
+	}
+	return string(o)
+}
+
+// syslogParamName replaces characters that are not allowed in param names
+func syslogParamName(i string) string {
+	o := []byte(syslogHeaderField(i, 32))
+	for idx, c := range o {
+		if c == '=' || c == ']' || c == '"' {
+			o[idx] = '_'
+		}
+	}
+	return string(o)
+}
+
+var syslogParamValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// syslogParamValue escapes characters that must be escaped in param values
+func syslogParamValue(i string) string {
+	return syslogParamValueReplacer.Replace(i)
+}
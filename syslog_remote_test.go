@@ -0,0 +1,303 @@
+package astilog
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astikit"
+)
+
+func TestSyslogSeverity(t *testing.T) {
+	for _, v := range []struct {
+		e int
+		l astikit.LoggerLevel
+	}{
+		{e: 7, l: astikit.LoggerLevelDebug},
+		{e: 6, l: astikit.LoggerLevelInfo},
+		{e: 4, l: astikit.LoggerLevelWarn},
+		{e: 3, l: astikit.LoggerLevelError},
+		{e: 2, l: astikit.LoggerLevelFatal},
+	} {
+		if g := syslogSeverity(v.l); v.e != g {
+			t.Errorf("expected %d, got %d", v.e, g)
+		}
+	}
+}
+
+func TestSyslogFacility(t *testing.T) {
+	for _, v := range []struct {
+		e int
+		n string
+	}{
+		{e: 1, n: ""},
+		{e: 3, n: "daemon"},
+		{e: 16, n: "LOCAL0"},
+	} {
+		g, err := syslogFacility(v.n)
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+		if v.e != g {
+			t.Errorf("expected %d, got %d", v.e, g)
+		}
+	}
+	if _, err := syslogFacility("invalid"); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
+
+func TestRemoteSyslogWriterMessage(t *testing.T) {
+	w := &remoteSyslogWriter{
+		c:        Configuration{AppName: "my app"},
+		facility: 16,
+		hostname: "host",
+	}
+	n := time.Date(2020, 1, 2, 3, 4, 5, 6000, time.UTC)
+	if e, g := "<130>1 2020-01-02T03:04:05.000006Z host my_app "+strconv.Itoa(os.Getpid())+` - [astilog@32473 a="1" b_c="d\"e\\f\]"] msg`, string(w.message(entry{
+		fs:  map[string]interface{}{"a": 1, "b c": `d"e\f]`},
+		l:   astikit.LoggerLevelFatal,
+		msg: "msg",
+		t:   n,
+	})); e != g {
+		t.Errorf("expected %s, got %s", e, g)
+	}
+	if e, g := "<135>1 2020-01-02T03:04:05.000006Z host my_app "+strconv.Itoa(os.Getpid())+" - -", string(w.message(entry{
+		l: astikit.LoggerLevelDebug,
+		t: n,
+	})); e != g {
+		t.Errorf("expected %s, got %s", e, g)
+	}
+}
+
+func TestRemoteSyslogWriterUDP(t *testing.T) {
+	// Listen
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(fmt.Errorf("listening failed: %w", err))
+	}
+	defer conn.Close()
+
+	// Create writer
+	w, err := newRemoteSyslogWriter(Configuration{
+		AppName:        "app",
+		SyslogAddress:  conn.LocalAddr().String(),
+		SyslogFacility: "local0",
+		SyslogNetwork:  SyslogNetworkUDP,
+	})
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating writer failed: %w", err))
+	}
+	defer w.Close()
+
+	// Write
+	if err = w.writeEntry(entry{
+		l:   astikit.LoggerLevelWarn,
+		msg: "msg",
+		t:   time.Now(),
+	}, nil); err != nil {
+		t.Fatal(fmt.Errorf("writing failed: %w", err))
+	}
+
+	// Read
+	b := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(time.Second)) //nolint: errcheck
+	n, _, err := conn.ReadFrom(b)
+	if err != nil {
+		t.Fatal(fmt.Errorf("reading failed: %w", err))
+	}
+	if g := string(b[:n]); !strings.HasPrefix(g, "<132>1 ") || !strings.HasSuffix(g, " - - msg") {
+		t.Errorf("invalid message %s", g)
+	}
+}
+
+func testRemoteSyslogWriterStream(t *testing.T, ln net.Listener, network string, tlsConfig *tls.Config) {
+	// Accept
+	ch := make(chan []string)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// Read octet-counted messages
+		var ms []string
+		r := bufio.NewReader(conn)
+		for i := 0; i < 2; i++ {
+			l, err := r.ReadString(' ')
+			if err != nil {
+				break
+			}
+			n, err := strconv.Atoi(strings.TrimSuffix(l, " "))
+			if err != nil {
+				break
+			}
+			b := make([]byte, n)
+			if _, err = io.ReadFull(r, b); err != nil {
+				break
+			}
+			ms = append(ms, string(b))
+		}
+		ch <- ms
+	}()
+
+	// Create writer
+	w, err := newRemoteSyslogWriter(Configuration{
+		SyslogAddress:   ln.Addr().String(),
+		SyslogNetwork:   network,
+		SyslogTLSConfig: tlsConfig,
+	})
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating writer failed: %w", err))
+	}
+	defer w.Close()
+
+	// Write
+	for _, l := range []astikit.LoggerLevel{astikit.LoggerLevelError, astikit.LoggerLevelDebug} {
+		if err = w.writeEntry(entry{
+			fs:  map[string]interface{}{"k": "v"},
+			l:   l,
+			msg: "msg\nwith newline",
+			t:   time.Now(),
+		}, nil); err != nil {
+			t.Fatal(fmt.Errorf("writing failed: %w", err))
+		}
+	}
+
+	// Assert
+	ms := <-ch
+	if e, g := 2, len(ms); e != g {
+		t.Fatalf("expected %d, got %d", e, g)
+	}
+	for idx, p := range []string{"<11>1 ", "<15>1 "} {
+		if !strings.HasPrefix(ms[idx], p) || !strings.HasSuffix(ms[idx], ` - [astilog@32473 k="v"] msg`+"\nwith newline") {
+			t.Errorf("invalid message %s", ms[idx])
+		}
+	}
+}
+
+func TestRemoteSyslogWriterTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(fmt.Errorf("listening failed: %w", err))
+	}
+	defer ln.Close()
+	testRemoteSyslogWriterStream(t, ln, SyslogNetworkTCP, nil)
+}
+
+func TestRemoteSyslogWriterTLS(t *testing.T) {
+	// Create certificate
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(fmt.Errorf("generating key failed: %w", err))
+	}
+	tpl := &x509.Certificate{
+		BasicConstraintsValid: true,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		NotAfter:              time.Now().Add(time.Hour),
+		NotBefore:             time.Now().Add(-time.Hour),
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "astilog"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tpl, tpl, &k.PublicKey, k)
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating certificate failed: %w", err))
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(fmt.Errorf("parsing certificate failed: %w", err))
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	// Listen
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{{
+		Certificate: [][]byte{der},
+		PrivateKey:  k,
+	}}})
+	if err != nil {
+		t.Fatal(fmt.Errorf("listening failed: %w", err))
+	}
+	defer ln.Close()
+	testRemoteSyslogWriterStream(t, ln, SyslogNetworkTLS, &tls.Config{RootCAs: pool})
+}
+
+func TestRemoteSyslogWriterReconnect(t *testing.T) {
+	// Get an address nothing is listening on
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(fmt.Errorf("listening failed: %w", err))
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	// Create writer while the collector is down
+	ss := &testNetStates{m: &sync.Mutex{}}
+	w, err := newRemoteSyslogWriter(Configuration{
+		NetStateHandler: ss.handler,
+		SyslogAddress:   addr,
+		SyslogNetwork:   SyslogNetworkTCP,
+	})
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating writer failed: %w", err))
+	}
+	defer w.Close()
+	if e, g := []NetState{NetStateDisconnected}, ss.get(); fmt.Sprint(e) != fmt.Sprint(g) {
+		t.Errorf("expected %v, got %v", e, g)
+	}
+
+	// Write
+	cs := &sinkCounters{}
+	if err = w.writeEntry(entry{
+		cs:  cs,
+		l:   astikit.LoggerLevelInfo,
+		msg: "msg",
+		t:   time.Now(),
+	}, nil); err != nil {
+		t.Fatal(fmt.Errorf("writing failed: %w", err))
+	}
+
+	// Buffered entries are not counted as written
+	if e, g := (sinkCounters{}), cs.load(); e != g {
+		t.Errorf("expected %+v, got %+v", e, g)
+	}
+
+	// Listen
+	if ln, err = net.Listen("tcp", addr); err != nil {
+		t.Skip(fmt.Errorf("listening failed: %w", err))
+	}
+	defer ln.Close()
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(fmt.Errorf("accepting failed: %w", err))
+	}
+	defer conn.Close()
+
+	// Buffered message should have been sent
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second)) //nolint: errcheck
+	l, err := bufio.NewReader(conn).ReadString(' ')
+	if err != nil {
+		t.Fatal(fmt.Errorf("reading failed: %w", err))
+	}
+	if _, err = strconv.Atoi(strings.TrimSuffix(l, " ")); err != nil {
+		t.Errorf("expected octet count, got %s", l)
+	}
+}
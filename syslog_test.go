@@ -0,0 +1,83 @@
+// +build !windows
+
+package astilog
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log/syslog"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astikit"
+)
+
+func TestSyslogWriter(t *testing.T) {
+	// Create temp dir
+	d, err := ioutil.TempDir("", "astilog_")
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating temp dir failed: %w", err))
+	}
+
+	// Make sure to delete directory
+	defer os.RemoveAll(d)
+
+	// Listen
+	p := filepath.Join(d, "syslog.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: p, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(fmt.Errorf("listening failed: %w", err))
+	}
+	defer conn.Close()
+
+	// Bypass syslog.New
+	old := syslogNew
+	syslogNew = func(priority syslog.Priority, tag string) (*syslog.Writer, error) {
+		return syslog.Dial("unixgram", p, priority, tag)
+	}
+	defer func() { syslogNew = old }()
+
+	// Create logger
+	l := New(Configuration{
+		AppName:        "app",
+		Format:         FormatMinimalist,
+		Level:          astikit.LoggerLevelDebug,
+		Out:            OutSyslog,
+		SyslogFacility: "local0",
+		SyslogTag:      "tag",
+	})
+	defer l.Close()
+
+	// Write
+	l.Debug("debug")
+	l.Info("info")
+	l.Warn("warn")
+	l.Error("error")
+	l.Write(astikit.LoggerLevelFatal, "fatal")
+
+	// Read
+	var gs []string
+	b := make([]byte, 1024)
+	for i := 0; i < 5; i++ {
+		conn.SetReadDeadline(time.Now().Add(time.Second)) //nolint: errcheck
+		n, err := conn.Read(b)
+		if err != nil {
+			t.Fatal(fmt.Errorf("reading failed: %w", err))
+		}
+
+		// Local syslog format is "<PRI>TIMESTAMP TAG[PID]: MSG"
+		m := string(b[:n])
+		if !strings.Contains(m, " tag[") {
+			t.Errorf("invalid tag in %s", m)
+		}
+		gs = append(gs, m[:strings.Index(m, ">")+1]+m[strings.Index(m, ": ")+2:])
+	}
+	if e := []string{"<135>debug\n", "<134>info\n", "<132>warn\n", "<131>error\n", "<130>fatal\n"}; !reflect.DeepEqual(e, gs) {
+		t.Errorf("expected %+v, got %+v", e, gs)
+	}
+}
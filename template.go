@@ -0,0 +1,224 @@
+package astilog
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/asticode/go-astikit"
+)
+
+const templateDefaultLayout = `{{.Level | levelColor | lpad 5}}[{{.Time | color "dim"}}]{{.Message}}{{with .Fields}}  {{.}}{{end}}`
+
+var templateColors = map[string]string{
+	"cyan":    colorKey,
+	"dim":     colorDim,
+	"grey":    colorGrey,
+	"magenta": colorMagenta,
+	"red":     colorRed,
+	"yellow":  colorYellow,
+}
+
+// templateFormatter formats entries with a text/template layout parsed once at creation
+type templateFormatter struct {
+	c         Configuration
+	color     bool
+	createdAt time.Time
+	p         *sync.Pool // Pool of *bytes.Buffer
+	t         *template.Template
+}
+
+func newTemplateFormatter(c Configuration, createdAt time.Time) (f *templateFormatter, err error) {
+	// Create
+	f = &templateFormatter{
+		c:         c,
+		color:     colorEnabled(c),
+		createdAt: createdAt,
+		p:         &sync.Pool{New: func() interface{} { return &bytes.Buffer{} }},
+	}
+
+	// Get layout
+	layout := c.Template
+	if layout == "" {
+		layout = templateDefaultLayout
+	}
+
+	// Parse
+	if f.t, err = template.New("astilog").Funcs(template.FuncMap{
+		"color":      f.colorize,
+		"levelColor": f.levelColor,
+		"lpad":       templateLeftPad,
+		"pad":        templatePad,
+	}).Parse(layout); err != nil {
+		err = fmt.Errorf("parsing template failed: %w", err)
+		return
+	}
+	return
+}
+
+// Format implements the Formatter interface
+func (f *templateFormatter) Format(e Entry) ([]byte, error) {
+	// Get buffer
+	buf := f.p.Get().(*bytes.Buffer)
+	defer f.p.Put(buf)
+	buf.Reset()
+
+	// Execute
+	if err := f.t.Execute(buf, templateData{
+		Message: e.Message,
+		Source:  e.Source,
+		e:       e,
+		f:       f,
+	}); err != nil {
+		return nil, fmt.Errorf("executing template failed: %w", err)
+	}
+
+	// Add newline
+	if !bytes.HasSuffix(buf.Bytes(), newLine) {
+		buf.Write(newLine)
+	}
+
+	// Buffer is reused
+	b := make([]byte, buf.Len())
+	copy(b, buf.Bytes())
+	return b, nil
+}
+
+func (f *templateFormatter) colorize(name string, v interface{}) string {
+	return string(colorAppend(nil, fmt.Sprint(v), templateColors[name], f.color))
+}
+
+func (f *templateFormatter) levelColor(v interface{}) string {
+	s := fmt.Sprint(v)
+	return string(colorAppend(nil, s, colorLevel(astikit.LoggerLevelFromString(strings.ToLower(strings.TrimSpace(s)))), f.color))
+}
+
+// templatePad pads the value with spaces on the right
+func templatePad(n int, v interface{}) string {
+	s := fmt.Sprint(v)
+	if l := templateVisibleLength(s); l < n {
+		s += strings.Repeat(" ", n-l)
+	}
+	return s
+}
+
+// templateLeftPad pads the value with spaces on the left
+func templateLeftPad(n int, v interface{}) string {
+	s := fmt.Sprint(v)
+	if l := templateVisibleLength(s); l < n {
+		s = strings.Repeat(" ", n-l) + s
+	}
+	return s
+}
+
+// templateVisibleLength returns the number of runes, ANSI escape codes excluded, so that
+// colorized values are padded properly
+func templateVisibleLength(s string) (n int) {
+	for i := 0; i < len(s); {
+		// Skip escape code
+		if s[i] == '\x1b' {
+			if j := strings.IndexByte(s[i:], 'm'); j >= 0 {
+				i += j + 1
+				continue
+			}
+		}
+
+		// Skip rune
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		n++
+	}
+	return
+}
+
+// templateData is the data provided to templates
+type templateData struct {
+	Message string
+	Source  string
+	e       Entry
+	f       *templateFormatter
+}
+
+// Field returns the value of a field or an empty string if it doesn't exist
+func (d templateData) Field(k string) interface{} {
+	if v, ok := d.e.Fields[k]; ok {
+		return v
+	}
+	return ""
+}
+
+// Fields returns all fields
+func (d templateData) Fields() templateFields {
+	return d.FieldsExcept()
+}
+
+// FieldsExcept returns all fields except the ones provided
+func (d templateData) FieldsExcept(ks ...string) (fs templateFields) {
+	// Loop through fields
+	for k, v := range d.e.Fields {
+		// Field is excluded
+		var excluded bool
+		for _, ek := range ks {
+			if k == ek {
+				excluded = true
+				break
+			}
+		}
+		if excluded {
+			continue
+		}
+
+		// Add
+		fs = append(fs, templateField{
+			Key:   k,
+			Value: fmt.Sprintf("%v", v),
+			color: d.f.color,
+		})
+	}
+
+	// Sort
+	sort.Slice(fs, func(i, j int) bool { return fs[i].Key+"="+fs[i].Value < fs[j].Key+"="+fs[j].Value })
+	return
+}
+
+// Level returns the uppercased level
+func (d templateData) Level() string {
+	return strings.ToUpper(d.e.Level.String())
+}
+
+// Time returns the time formatted with the TimestampFormat option or, if empty, the
+// number of seconds since the logger creation
+func (d templateData) Time() string {
+	if d.f.c.TimestampFormat == "" {
+		return string(astikit.BytesPad([]byte(strconv.Itoa(int(d.e.Time.Sub(d.f.createdAt).Seconds()))), '0', 4))
+	}
+	return d.e.Time.Format(d.f.c.TimestampFormat)
+}
+
+type templateField struct {
+	Key   string
+	Value string
+	color bool
+}
+
+// String implements the fmt.Stringer interface
+func (f templateField) String() string {
+	return string(colorAppend(nil, f.Key, colorKey, f.color)) + "=" + f.Value
+}
+
+type templateFields []templateField
+
+// String implements the fmt.Stringer interface
+func (fs templateFields) String() string {
+	ss := make([]string, 0, len(fs))
+	for _, f := range fs {
+		ss = append(ss, f.String())
+	}
+	return strings.Join(ss, " ")
+}
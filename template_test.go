@@ -0,0 +1,64 @@
+package astilog
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astikit"
+)
+
+func TestTemplateFormatter(t *testing.T) {
+	oldNow := now
+	defer func() { now = oldNow }()
+	now = func() time.Time { return time.Unix(5, 0).UTC() }
+
+	// Loop through cases
+	fs := map[string]interface{}{
+		"k1":     "v1",
+		"k2":     2,
+		"source": "main.go:1",
+	}
+	for _, v := range []struct {
+		c Configuration
+		e string
+		l astikit.LoggerLevel
+	}{
+		{e: " WARN[0005]msg  k1=v1 k2=2 source=main.go:1\n", l: astikit.LoggerLevelWarn},
+		{c: Configuration{Template: "{{.Level | pad 5}}|{{.Message}}|{{.Field \"k1\"}}|{{.Field \"k3\"}}|{{.FieldsExcept \"k1\" \"source\"}}\n"}, e: "INFO |msg|v1||k2=2\n", l: astikit.LoggerLevelInfo},
+		{c: Configuration{Template: "{{.Time}} {{.Source}} {{range .Fields}}[{{.Key}}:{{.Value}}]{{end}}", TimestampFormat: time.RFC3339}, e: "1970-01-01T00:00:05Z main.go:1 [k1:v1][k2:2][source:main.go:1]\n", l: astikit.LoggerLevelInfo},
+		{c: Configuration{Color: ColorAlways, Template: "{{.Level | levelColor | lpad 6}}|{{.Message | color \"cyan\"}}|{{.Message | color \"unknown\"}}|{{.FieldsExcept \"k2\" \"source\"}}"}, e: " \x1b[31mERROR\x1b[0m|\x1b[36mmsg\x1b[0m|msg|\x1b[36mk1\x1b[0m=v1\n", l: astikit.LoggerLevelError},
+	} {
+		f, err := newTemplateFormatter(v.c, time.Unix(0, 0).UTC())
+		if err != nil {
+			t.Fatal(fmt.Errorf("creating formatter failed: %w", err))
+		}
+		b, err := f.Format(Entry{
+			Fields:  fs,
+			Level:   v.l,
+			Message: "msg",
+			Source:  "main.go:1",
+			Time:    now(),
+		})
+		if err != nil {
+			t.Fatal(fmt.Errorf("formatting failed: %w", err))
+		}
+		if g := string(b); v.e != g {
+			t.Errorf("expected %q, got %q", v.e, g)
+		}
+	}
+
+	// Invalid template
+	if _, err := newTemplateFormatter(Configuration{Template: "{{.Message"}, time.Unix(0, 0)); err == nil {
+		t.Error("expected error, got nil")
+	}
+
+	// Execution failure
+	f, err := newTemplateFormatter(Configuration{Template: "{{.Unknown}}"}, time.Unix(0, 0))
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating formatter failed: %w", err))
+	}
+	if _, err = f.Format(Entry{}); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
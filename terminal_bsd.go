@@ -0,0 +1,7 @@
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package astilog
+
+import "syscall"
+
+const ioctlReadTermios = syscall.TIOCGETA
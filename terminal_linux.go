@@ -0,0 +1,5 @@
+package astilog
+
+import "syscall"
+
+const ioctlReadTermios = syscall.TCGETS
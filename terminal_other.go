@@ -0,0 +1,6 @@
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!windows
+
+package astilog
+
+// isTerminalFd always returns false since terminals can't be detected on this platform
+func isTerminalFd(fd uintptr) bool { return false }
@@ -0,0 +1,16 @@
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package astilog
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// isTerminalFd returns whether reading the terminal attributes of the file descriptor
+// succeeds
+func isTerminalFd(fd uintptr) bool {
+	var t syscall.Termios
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, ioctlReadTermios, uintptr(unsafe.Pointer(&t)))
+	return errno == 0
+}
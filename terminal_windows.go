@@ -0,0 +1,9 @@
+package astilog
+
+import "syscall"
+
+// isTerminalFd returns whether getting the console mode of the handle succeeds
+func isTerminalFd(fd uintptr) bool {
+	var m uint32
+	return syscall.GetConsoleMode(syscall.Handle(fd), &m) == nil
+}
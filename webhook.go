@@ -0,0 +1,375 @@
+package astilog
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/asticode/go-astikit"
+)
+
+// Webhook encodings
+const (
+	WebhookEncodingJSON   = "json"
+	WebhookEncodingNDJSON = "ndjson"
+)
+
+const webhookSpillFilename = "astilog_webhook.spill"
+
+// webhookWriter sends entries in batches to an HTTP endpoint either as a JSON array or
+// as NDJSON. Batches that can't be sent are spilled in memory or on disk and are sent
+// once the endpoint is back up.
+type webhookWriter struct {
+	*batchWriter
+	c  Configuration
+	f  *jsonFormatter
+	hc *httpClient
+}
+
+func newWebhookWriter(c Configuration, createdAt time.Time) (w *webhookWriter, err error) {
+	// Check url
+	if c.WebhookURL == "" {
+		err = errors.New("webhook url is empty")
+		return
+	}
+
+	// Check encoding
+	switch c.WebhookEncoding {
+	case "", WebhookEncodingJSON, WebhookEncodingNDJSON:
+	default:
+		err = fmt.Errorf("unknown webhook encoding %s", c.WebhookEncoding)
+		return
+	}
+
+	// Create
+	w = &webhookWriter{
+		c:  c,
+		f:  newJSONFormatter(c, createdAt),
+		hc: newHTTPClient(c),
+	}
+
+	// Get spill max size
+	spillMaxSize := c.WebhookSpillMaxSize
+	if spillMaxSize <= 0 {
+		spillMaxSize = batchDefaultBufferSize
+	}
+
+	// Create spiller
+	var s batchSpiller
+	if c.WebhookSpillDir != "" {
+		// Create directory
+		if err = os.MkdirAll(c.WebhookSpillDir, 0755); err != nil {
+			err = fmt.Errorf("creating %s failed: %w", c.WebhookSpillDir, err)
+			return
+		}
+		s = newDiskBatchSpiller(filepath.Join(c.WebhookSpillDir, webhookSpillFilename), spillMaxSize)
+	} else {
+		s = newMemoryBatchSpiller(spillMaxSize)
+	}
+
+	// Create batch writer
+	w.batchWriter = newBatchWriter(c, w.send, s)
+	return
+}
+
+// Write implements the io.Writer interface
+func (w *webhookWriter) Write(p []byte) (int, error) {
+	if err := w.writeEntry(entry{
+		l:   astikit.LoggerLevelInfo,
+		msg: string(bytes.TrimRight(p, "\n")),
+		t:   now(),
+	}, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// buildsPayload implements the payloadBuilder interface
+func (w *webhookWriter) buildsPayload() bool {
+	return true
+}
+
+func (w *webhookWriter) writeEntry(e entry, _ []byte) error {
+	// Format
+	b, err := w.f.Format(e.export())
+	if err != nil {
+		return fmt.Errorf("formatting failed: %w", err)
+	}
+
+	// Add
+	return w.add(batchItem{
+		b: bytes.TrimRight(b, "\n"),
+		e: e,
+	})
+}
+
+func (w *webhookWriter) send(is []batchItem) (retry, dropped []batchItem, err error) {
+	// Create body
+	buf := &bytes.Buffer{}
+	contentType := "application/json"
+	if w.c.WebhookEncoding == WebhookEncodingNDJSON {
+		contentType = "application/x-ndjson"
+		for _, i := range is {
+			buf.Write(i.b)
+			buf.Write(newLine)
+		}
+	} else {
+		buf.WriteByte('[')
+		for idx, i := range is {
+			if idx > 0 {
+				buf.WriteByte(',')
+			}
+			buf.Write(i.b)
+		}
+		buf.WriteByte(']')
+	}
+	b := buf.Bytes()
+
+	// Compress
+	var headers map[string]string
+	if w.c.WebhookGzip {
+		// Compress
+		cb := &bytes.Buffer{}
+		gw := gzip.NewWriter(cb)
+		if _, err = gw.Write(b); err != nil {
+			err = fmt.Errorf("compressing failed: %w", err)
+			return
+		}
+		if err = gw.Close(); err != nil {
+			err = fmt.Errorf("closing gzip writer failed: %w", err)
+			return
+		}
+		b = cb.Bytes()
+
+		// Add header
+		headers = map[string]string{"Content-Encoding": "gzip"}
+	}
+
+	// Send
+	var retryable bool
+	if _, retryable, err = w.hc.do(http.MethodPost, w.c.WebhookURL, contentType, b, headers); err != nil {
+		if retryable {
+			retry = is
+		}
+		return
+	}
+	return
+}
+
+// memoryBatchSpiller stores spilled items in memory
+type memoryBatchSpiller struct {
+	is      []batchItem
+	m       *sync.Mutex // Locks is and size
+	maxSize int
+	size    int
+}
+
+func newMemoryBatchSpiller(maxSize int) *memoryBatchSpiller {
+	return &memoryBatchSpiller{
+		m:       &sync.Mutex{},
+		maxSize: maxSize,
+	}
+}
+
+func (s *memoryBatchSpiller) spill(is []batchItem) error {
+	// Lock
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	// Get size
+	var size int
+	for _, i := range is {
+		size += len(i.b)
+	}
+
+	// Spill is full
+	if s.size+size > s.maxSize {
+		return errors.New("memory spill is full")
+	}
+
+	// Add
+	s.is = append(s.is, is...)
+	s.size += size
+	return nil
+}
+
+func (s *memoryBatchSpiller) peek(maxSize int) ([]batchItem, error) {
+	// Lock
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	// Get items
+	var n, size int
+	for n < len(s.is) && (n == 0 || size+len(s.is[n].b) <= maxSize) {
+		size += len(s.is[n].b)
+		n++
+	}
+	return s.is[:n:n], nil
+}
+
+func (s *memoryBatchSpiller) discard(n int) error {
+	// Lock
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	// Discard
+	if n > len(s.is) {
+		n = len(s.is)
+	}
+	for _, i := range s.is[:n] {
+		s.size -= len(i.b)
+	}
+	s.is = s.is[n:]
+	return nil
+}
+
+// diskBatchSpiller stores spilled items in a file, one item per line, so that they
+// survive a restart. Items must not contain newlines.
+type diskBatchSpiller struct {
+	m       *sync.Mutex // Locks the file
+	maxSize int
+	path    string
+}
+
+func newDiskBatchSpiller(path string, maxSize int) *diskBatchSpiller {
+	return &diskBatchSpiller{
+		m:       &sync.Mutex{},
+		maxSize: maxSize,
+		path:    path,
+	}
+}
+
+func (s *diskBatchSpiller) spill(is []batchItem) (err error) {
+	// Lock
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	// Create buffer
+	buf := &bytes.Buffer{}
+	for _, i := range is {
+		buf.Write(i.b)
+		buf.Write(newLine)
+	}
+
+	// Open file
+	var f *os.File
+	if f, err = os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err != nil {
+		err = fmt.Errorf("opening %s failed: %w", s.path, err)
+		return
+	}
+	defer f.Close()
+
+	// Spill is full
+	var fi os.FileInfo
+	if fi, err = f.Stat(); err != nil {
+		err = fmt.Errorf("stating %s failed: %w", s.path, err)
+		return
+	}
+	if fi.Size()+int64(buf.Len()) > int64(s.maxSize) {
+		err = fmt.Errorf("disk spill %s is full", s.path)
+		return
+	}
+
+	// Write
+	if _, err = f.Write(buf.Bytes()); err != nil {
+		err = fmt.Errorf("writing to %s failed: %w", s.path, err)
+		return
+	}
+	return
+}
+
+func (s *diskBatchSpiller) peek(maxSize int) (is []batchItem, err error) {
+	// Lock
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	// Open file
+	var f *os.File
+	if f, err = os.Open(s.path); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+			return
+		}
+		err = fmt.Errorf("opening %s failed: %w", s.path, err)
+		return
+	}
+	defer f.Close()
+
+	// Loop through lines
+	var size int
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 64*1024), s.maxSize+1)
+	for sc.Scan() {
+		// Batch is full
+		if len(is) > 0 && size+len(sc.Bytes()) > maxSize {
+			break
+		}
+
+		// Add
+		b := make([]byte, len(sc.Bytes()))
+		copy(b, sc.Bytes())
+		is = append(is, batchItem{b: b})
+		size += len(b)
+	}
+	if err = sc.Err(); err != nil {
+		err = fmt.Errorf("scanning %s failed: %w", s.path, err)
+		return
+	}
+	return
+}
+
+func (s *diskBatchSpiller) discard(n int) (err error) {
+	// Lock
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	// Read file
+	var b []byte
+	if b, err = ioutil.ReadFile(s.path); err != nil {
+		if os.IsNotExist(err) {
+			err = nil
+			return
+		}
+		err = fmt.Errorf("reading %s failed: %w", s.path, err)
+		return
+	}
+
+	// Remove lines
+	for ; n > 0 && len(b) > 0; n-- {
+		if idx := bytes.IndexByte(b, '\n'); idx >= 0 {
+			b = b[idx+1:]
+		} else {
+			b = nil
+		}
+	}
+
+	// Nothing left
+	if len(b) == 0 {
+		if err = os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+			err = fmt.Errorf("removing %s failed: %w", s.path, err)
+			return
+		}
+		err = nil
+		return
+	}
+
+	// Write remaining lines atomically
+	tmp := s.path + ".tmp"
+	if err = ioutil.WriteFile(tmp, b, 0644); err != nil {
+		err = fmt.Errorf("writing to %s failed: %w", tmp, err)
+		return
+	}
+	if err = os.Rename(tmp, s.path); err != nil {
+		err = fmt.Errorf("renaming %s into %s failed: %w", tmp, s.path, err)
+		return
+	}
+	return
+}
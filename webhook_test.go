@@ -0,0 +1,199 @@
+package astilog
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testWebhookServer struct {
+	bodies  []string
+	headers []http.Header
+	m       *sync.Mutex
+	up      bool
+}
+
+func newTestWebhookServer() *testWebhookServer {
+	return &testWebhookServer{m: &sync.Mutex{}}
+}
+
+func (s *testWebhookServer) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	// Read body
+	var rd io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		rd = gr
+	}
+	b, err := ioutil.ReadAll(rd)
+	if err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.bodies = append(s.bodies, string(b))
+	s.headers = append(s.headers, r.Header)
+
+	// Server is down
+	if !s.up {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+}
+
+func (s *testWebhookServer) setUp(up bool) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.up = up
+}
+
+func testWebhookWriter(t *testing.T, c Configuration, es []string) *testWebhookServer {
+	// Mock now
+	oldNow := now
+	defer func() { now = oldNow }()
+	now = func() time.Time { return time.Unix(5, 0) }
+
+	// Create server
+	ts := newTestWebhookServer()
+	s := httptest.NewServer(ts)
+	defer s.Close()
+
+	// Create writer
+	c.BatchInterval = time.Hour
+	c.BatchMaxRetries = -1
+	c.TimestampFormat = "2006"
+	c.WebhookURL = s.URL
+	w, err := newWebhookWriter(c, time.Now())
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating writer failed: %w", err))
+	}
+	defer w.Close()
+
+	// Write while the server is down
+	for _, msg := range []string{"msg1", "msg2"} {
+		if _, err = w.Write([]byte(msg + "\n")); err != nil {
+			t.Fatal(fmt.Errorf("writing failed: %w", err))
+		}
+	}
+	if err = w.Flush(); err != nil {
+		t.Fatal(fmt.Errorf("flushing failed: %w", err))
+	}
+
+	// Write once the server is up
+	ts.setUp(true)
+	if _, err = w.Write([]byte("msg3\n")); err != nil {
+		t.Fatal(fmt.Errorf("writing failed: %w", err))
+	}
+	if err = w.Flush(); err != nil {
+		t.Fatal(fmt.Errorf("flushing failed: %w", err))
+	}
+
+	// Assert
+	ts.m.Lock()
+	defer ts.m.Unlock()
+	if !reflect.DeepEqual(es, ts.bodies) {
+		t.Errorf("expected %+v, got %+v", es, ts.bodies)
+	}
+	if e, g := uint64(0), w.Dropped(); e != g {
+		t.Errorf("expected %d, got %d", e, g)
+	}
+	return ts
+}
+
+func TestWebhookWriterMemory(t *testing.T) {
+	ts := testWebhookWriter(t, Configuration{HTTPHeaders: map[string]string{"Authorization": "Bearer token"}}, []string{
+		`[{"level":"info","msg":"msg1","time":"1970"},{"level":"info","msg":"msg2","time":"1970"}]`,
+		`[{"level":"info","msg":"msg1","time":"1970"},{"level":"info","msg":"msg2","time":"1970"}]`,
+		`[{"level":"info","msg":"msg3","time":"1970"}]`,
+	})
+	for _, h := range ts.headers {
+		if e, g := "Bearer token", h.Get("Authorization"); e != g {
+			t.Errorf("expected %s, got %s", e, g)
+		}
+		if e, g := "application/json", h.Get("Content-Type"); e != g {
+			t.Errorf("expected %s, got %s", e, g)
+		}
+	}
+}
+
+func TestWebhookWriterDisk(t *testing.T) {
+	// Create dir
+	dir, err := ioutil.TempDir("", "astilog_")
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating temp dir failed: %w", err))
+	}
+	defer os.RemoveAll(dir)
+
+	// Test
+	ts := testWebhookWriter(t, Configuration{
+		WebhookEncoding: WebhookEncodingNDJSON,
+		WebhookGzip:     true,
+		WebhookSpillDir: dir,
+	}, []string{
+		`{"level":"info","msg":"msg1","time":"1970"}` + "\n" + `{"level":"info","msg":"msg2","time":"1970"}` + "\n",
+		`{"level":"info","msg":"msg1","time":"1970"}` + "\n" + `{"level":"info","msg":"msg2","time":"1970"}` + "\n",
+		`{"level":"info","msg":"msg3","time":"1970"}` + "\n",
+	})
+	for _, h := range ts.headers {
+		if e, g := "application/x-ndjson", h.Get("Content-Type"); e != g {
+			t.Errorf("expected %s, got %s", e, g)
+		}
+	}
+
+	// Spill file should have been removed
+	if _, err = os.Stat(filepath.Join(dir, webhookSpillFilename)); !os.IsNotExist(err) {
+		t.Errorf("expected spill file to be removed, got %v", err)
+	}
+}
+
+func TestDiskBatchSpiller(t *testing.T) {
+	// Create dir
+	dir, err := ioutil.TempDir("", "astilog_")
+	if err != nil {
+		t.Fatal(fmt.Errorf("creating temp dir failed: %w", err))
+	}
+	defer os.RemoveAll(dir)
+
+	// Spill
+	s := newDiskBatchSpiller(filepath.Join(dir, "spill"), 12)
+	if err = s.spill([]batchItem{{b: []byte("1")}, {b: []byte("22")}, {b: []byte("333")}}); err != nil {
+		t.Fatal(fmt.Errorf("spilling failed: %w", err))
+	}
+	if err = s.spill([]batchItem{{b: []byte("4444")}}); err == nil {
+		t.Error("expected error, got nil")
+	}
+
+	// Peek
+	is, err := s.peek(3)
+	if err != nil {
+		t.Fatal(fmt.Errorf("peeking failed: %w", err))
+	}
+	if e, g := []batchItem{{b: []byte("1")}, {b: []byte("22")}}, is; !reflect.DeepEqual(e, g) {
+		t.Errorf("expected %+v, got %+v", e, g)
+	}
+
+	// Discard
+	if err = s.discard(2); err != nil {
+		t.Fatal(fmt.Errorf("discarding failed: %w", err))
+	}
+	if is, err = s.peek(3); err != nil {
+		t.Fatal(fmt.Errorf("peeking failed: %w", err))
+	}
+	if e, g := []batchItem{{b: []byte("333")}}, is; !reflect.DeepEqual(e, g) {
+		t.Errorf("expected %+v, got %+v", e, g)
+	}
+}